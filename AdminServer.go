@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/VictoriaMetrics/metrics"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+)
+
+const adminShutdownTimeout = time.Second * 5
+
+// AdminServer serves liveness and readiness probes, metrics and optionally pprof on a separate address,
+// it should not be exposed to the public.
+type AdminServer struct {
+	listen string
+	pprof  bool
+	// ready returns the reason why the app is not ready, nil when it is ready
+	ready  func() error
+	logger *slog.Logger
+}
+
+func NewAdminServer(listen string, pprof bool, ready func() error, logger *slog.Logger) *AdminServer {
+	return &AdminServer{
+		listen: listen,
+		pprof:  pprof,
+		ready:  ready,
+		logger: logger,
+	}
+}
+
+// Execute serves requests until ctx is cancelled, then waits for active requests to finish.
+func (server *AdminServer) Execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	httpServer := &http.Server{
+		Addr:              server.listen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), adminShutdownTimeout)
+		err = httpServer.Shutdown(shutdownCtx)
+		cancel()
+
+	case err = <-serverErr:
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		AdminServerErrorCount.Inc()
+		server.logger.With(errorLogAttrs(err)...).Error("Admin server error", slog.String("listen", server.listen))
+	}
+}
+
+func (server *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", server.serveHealth)
+	mux.HandleFunc("/readyz", server.serveReady)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		metrics.WritePrometheus(w, true)
+	})
+
+	if server.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return mux
+}
+
+func (server *AdminServer) serveHealth(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (server *AdminServer) serveReady(w http.ResponseWriter, r *http.Request) {
+	err := server.ready()
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdminServer_Handler(t *testing.T) {
+	var readyErr error
+	server := NewAdminServer(":0", false, func() error { return readyErr }, newLogger(&bytes.Buffer{}, logFormatText))
+	handler := server.Handler()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("healthz", func(t *testing.T) {
+		readyErr = ErrPollingNotStarted
+		defer func() { readyErr = nil }()
+
+		recorder := serve("/healthz")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("readyz", func(t *testing.T) {
+		recorder := serve("/readyz")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		readyErr = ErrScoreCircuitOpen
+		defer func() { readyErr = nil }()
+
+		recorder = serve("/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), ErrScoreCircuitOpen.Error())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		OnErrorCount.Get()
+
+		recorder := serve("/metrics")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `error_count{type="onError"}`)
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, serve("/debug/pprof/").Code)
+
+		server.pprof = true
+		defer func() { server.pprof = false }()
+
+		recorder := httptest.NewRecorder()
+		server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestAdminServer_Execute(t *testing.T) {
+	t.Run("shutdown", func(t *testing.T) {
+		listen := getFreeListenAddress(t)
+		server := NewAdminServer(listen, false, func() error { return nil }, newLogger(&bytes.Buffer{}, logFormatText))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go server.Execute(ctx, wg)
+
+		var response *http.Response
+		var err error
+		assert.Eventually(t, func() bool {
+			response, err = http.Get("http://" + listen + "/healthz")
+			return err == nil
+		}, time.Second, time.Millisecond*10)
+
+		if response != nil {
+			body, _ := io.ReadAll(response.Body)
+			_ = response.Body.Close()
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			assert.Equal(t, "ok\n", string(body))
+		}
+
+		cancel()
+		wg.Wait()
+
+		_, err = http.Get("http://" + listen + "/healthz")
+		assert.Error(t, err)
+	})
+
+	t.Run("listen_error", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		server := NewAdminServer("wrong-address", false, func() error { return nil }, newLogger(out, logFormatText))
+		errorCountBefore := AdminServerErrorCount.Get()
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		server.Execute(context.Background(), wg)
+		wg.Wait()
+
+		assert.Equal(t, errorCountBefore+1, AdminServerErrorCount.Get())
+		assert.Contains(t, out.String(), `msg="Admin server error"`)
+	})
+}
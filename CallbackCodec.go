@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// callbackPayloadVersion is changed when fields are changed, so buttons of previous versions are rejected as expired.
+const callbackPayloadVersion = "1"
+
+const callbackSignatureLength = 6
+
+var ErrCallbackPayloadExpired = errors.New("callback payload expired")
+
+var ErrCallbackPayloadInvalid = errors.New("callback payload invalid")
+
+type CallbackPayload struct {
+	DisciplineId int
+	Page         int
+	Semester     int
+	ViewMode     int
+}
+
+// viewModeNotification is ViewMode of the button placed on score changed notification,
+// the notification is kept and scores are sent as a new message.
+const viewModeNotification = 1
+
+func (payload CallbackPayload) fields() []int {
+	return []int{payload.DisciplineId, payload.Page, payload.Semester, payload.ViewMode}
+}
+
+// CallbackCodec encodes payload as "<version>.<field>...<signature>" with base36 fields, trailing zero fields
+// are omitted. The signature is truncated HMAC of button unique and payload, so data could not be forged
+// or moved to another button.
+type CallbackCodec struct {
+	secret []byte
+}
+
+func NewCallbackCodec(secret string) *CallbackCodec {
+	return &CallbackCodec{
+		secret: []byte(secret),
+	}
+}
+
+func (codec *CallbackCodec) Encode(unique string, payload CallbackPayload) string {
+	fields := payload.fields()
+	for len(fields) > 0 && fields[len(fields)-1] == 0 {
+		fields = fields[:len(fields)-1]
+	}
+
+	data := strings.Builder{}
+	data.WriteString(callbackPayloadVersion)
+	for _, field := range fields {
+		data.WriteByte('.')
+		data.WriteString(strconv.FormatInt(int64(field), 36))
+	}
+	signature := codec.sign(unique, data.String())
+	data.WriteByte('.')
+	data.WriteString(signature)
+
+	return data.String()
+}
+
+func (codec *CallbackCodec) Decode(unique string, data string) (payload CallbackPayload, err error) {
+	separatorIndex := strings.LastIndexByte(data, '.')
+	versionIndex := strings.IndexByte(data, '.')
+	if separatorIndex == -1 || data[:versionIndex] != callbackPayloadVersion {
+		return payload, ErrCallbackPayloadExpired
+	}
+
+	signed, signature := data[:separatorIndex], data[separatorIndex+1:]
+	if !hmac.Equal([]byte(signature), []byte(codec.sign(unique, signed))) {
+		return payload, ErrCallbackPayloadInvalid
+	}
+
+	fields := make([]int, len(payload.fields()))
+	encodedFields := strings.Split(signed, ".")[1:]
+	if len(encodedFields) > len(fields) {
+		return payload, ErrCallbackPayloadInvalid
+	}
+
+	for i, encodedField := range encodedFields {
+		field, parseErr := strconv.ParseInt(encodedField, 36, 32)
+		if parseErr != nil {
+			return payload, ErrCallbackPayloadInvalid
+		}
+		fields[i] = int(field)
+	}
+
+	return CallbackPayload{
+		DisciplineId: fields[0],
+		Page:         fields[1],
+		Semester:     fields[2],
+		ViewMode:     fields[3],
+	}, nil
+}
+
+func (codec *CallbackCodec) sign(unique string, data string) string {
+	mac := hmac.New(sha256.New, codec.secret)
+	mac.Write([]byte(unique))
+	mac.Write([]byte{'|'})
+	mac.Write([]byte(data))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureLength])
+}
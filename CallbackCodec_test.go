@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestCallbackCodec(t *testing.T) {
+	codec := NewCallbackCodec("test-secret")
+
+	t.Run("round_trip", func(t *testing.T) {
+		payloads := []CallbackPayload{
+			{},
+			{DisciplineId: 199},
+			{Page: 2},
+			{DisciplineId: 199, Page: 3, Semester: 2, ViewMode: 1},
+		}
+
+		for _, payload := range payloads {
+			actual, err := codec.Decode("discipline", codec.Encode("discipline", payload))
+
+			assert.NoError(t, err)
+			assert.Equal(t, payload, actual)
+		}
+	})
+
+	t.Run("fits_callback_data_limit", func(t *testing.T) {
+		payload := CallbackPayload{
+			DisciplineId: math.MaxInt32,
+			Page:         math.MaxInt32,
+			Semester:     math.MaxInt32,
+			ViewMode:     math.MaxInt32,
+		}
+
+		assert.LessOrEqual(t, len("\fdiscipline|"+codec.Encode("discipline", payload)), 64)
+	})
+
+	t.Run("forged", func(t *testing.T) {
+		data := codec.Encode("discipline", CallbackPayload{DisciplineId: 199})
+
+		_, err := codec.Decode("discipline", data[:len(data)-1]+"A")
+		assert.Equal(t, ErrCallbackPayloadInvalid, err)
+
+		_, err = codec.Decode("list", data)
+		assert.Equal(t, ErrCallbackPayloadInvalid, err)
+
+		_, err = NewCallbackCodec("other-secret").Decode("discipline", data)
+		assert.Equal(t, ErrCallbackPayloadInvalid, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := codec.Decode("discipline", "199")
+		assert.Equal(t, ErrCallbackPayloadExpired, err)
+
+		_, err = codec.Decode("discipline", "")
+		assert.Equal(t, ErrCallbackPayloadExpired, err)
+
+		_, err = codec.Decode("discipline", "2.5j."+codec.sign("discipline", "2.5j"))
+		assert.Equal(t, ErrCallbackPayloadExpired, err)
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// Telegram allows about one message per second to the same private chat and 20 messages per minute to a group.
+const chatRateLimiterBurst = 3
+
+const chatRateLimiterIdleTTL = time.Minute * 5
+
+type chatRateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// ChatRateLimiter keeps a separate rate.Limiter for each chat ID and drops limiters of idle chats.
+type ChatRateLimiter struct {
+	privateLimit rate.Limit
+	groupLimit   rate.Limit
+	burst        int
+	idleTTL      time.Duration
+
+	mutex     sync.Mutex
+	limiters  map[int64]*chatRateLimiterEntry
+	lastSweep time.Time
+}
+
+func NewChatRateLimiter(privateLimit rate.Limit, groupLimit rate.Limit, burst int, idleTTL time.Duration) *ChatRateLimiter {
+	return &ChatRateLimiter{
+		privateLimit: privateLimit,
+		groupLimit:   groupLimit,
+		burst:        burst,
+		idleTTL:      idleTTL,
+		limiters:     make(map[int64]*chatRateLimiterEntry),
+		lastSweep:    time.Now(),
+	}
+}
+
+func NewDefaultChatRateLimiter() *ChatRateLimiter {
+	return NewChatRateLimiter(
+		rate.Every(time.Second), rate.Every(time.Minute/20),
+		chatRateLimiterBurst, chatRateLimiterIdleTTL,
+	)
+}
+
+func (chatRateLimiter *ChatRateLimiter) Wait(ctx context.Context, chatId int64) error {
+	start := time.Now()
+	err := chatRateLimiter.get(chatId, start).Wait(ctx)
+	ChatRateLimitWaitDuration.UpdateDuration(start)
+
+	return err
+}
+
+func (chatRateLimiter *ChatRateLimiter) Len() int {
+	chatRateLimiter.mutex.Lock()
+	defer chatRateLimiter.mutex.Unlock()
+
+	return len(chatRateLimiter.limiters)
+}
+
+func (chatRateLimiter *ChatRateLimiter) get(chatId int64, now time.Time) *rate.Limiter {
+	chatRateLimiter.mutex.Lock()
+	defer chatRateLimiter.mutex.Unlock()
+
+	if now.Sub(chatRateLimiter.lastSweep) >= chatRateLimiter.idleTTL {
+		chatRateLimiter.evictIdle(now)
+	}
+
+	entry, exists := chatRateLimiter.limiters[chatId]
+	if !exists {
+		limit := chatRateLimiter.privateLimit
+		// group and channel chat IDs are negative
+		if chatId < 0 {
+			limit = chatRateLimiter.groupLimit
+		}
+
+		entry = &chatRateLimiterEntry{
+			limiter: rate.NewLimiter(limit, chatRateLimiter.burst),
+		}
+		chatRateLimiter.limiters[chatId] = entry
+	}
+
+	entry.lastUsed = now
+	return entry.limiter
+}
+
+func (chatRateLimiter *ChatRateLimiter) evictIdle(now time.Time) {
+	for chatId, entry := range chatRateLimiter.limiters {
+		if now.Sub(entry.lastUsed) >= chatRateLimiter.idleTTL {
+			delete(chatRateLimiter.limiters, chatId)
+			ChatRateLimiterEvictedTotal.Inc()
+		}
+	}
+
+	chatRateLimiter.lastSweep = now
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"testing"
+	"time"
+)
+
+func TestChatRateLimiter_Wait(t *testing.T) {
+	t.Run("per_chat", func(t *testing.T) {
+		chatRateLimiter := NewChatRateLimiter(rate.Every(time.Millisecond*200), rate.Every(time.Second), 1, time.Minute)
+
+		start := time.Now()
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 1))
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 2))
+		assert.Less(t, time.Since(start), time.Millisecond*100)
+
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 1))
+		assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*150)
+		assert.Equal(t, 2, chatRateLimiter.Len())
+	})
+
+	t.Run("group_limit", func(t *testing.T) {
+		chatRateLimiter := NewChatRateLimiter(rate.Inf, rate.Every(time.Minute), 1, time.Minute)
+
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), -100))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		assert.Error(t, chatRateLimiter.Wait(ctx, -100))
+
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 100))
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 100))
+	})
+
+	t.Run("evict_idle", func(t *testing.T) {
+		chatRateLimiter := NewChatRateLimiter(rate.Inf, rate.Inf, 1, time.Millisecond*50)
+		evictedBefore := ChatRateLimiterEvictedTotal.Get()
+
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 1))
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 2))
+		assert.Equal(t, 2, chatRateLimiter.Len())
+
+		time.Sleep(time.Millisecond * 60)
+
+		assert.NoError(t, chatRateLimiter.Wait(context.Background(), 3))
+		assert.Equal(t, 1, chatRateLimiter.Len())
+		assert.Equal(t, evictedBefore+2, ChatRateLimiterEvictedTotal.Get())
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	tele "gopkg.in/telebot.v3"
+	"time"
+)
+
+const defaultHandlerTimeout = time.Second * 15
+
+const defaultHandlerConcurrency = 64
+
+const contextUpdateCtxKey = "updateCtx"
+
+var ErrHandlerTimeout = errors.New("handler timeout exceeded")
+
+var ErrHandlerQueueTimeout = errors.New("handler queue timeout exceeded")
+
+// HandlerLimiter limits count of concurrently processed updates and attaches deadline to each of them.
+// The slot is released only when the handler is finished, so hung handlers could not exceed the limit
+// even after they are reported as timed out.
+type HandlerLimiter struct {
+	ctx     context.Context
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+// NewHandlerLimiter creates limiter, concurrency 0 disables the limit and timeout 0 disables the deadline.
+// ctx is the parent of update contexts, it is cancelled on shutdown.
+func NewHandlerLimiter(ctx context.Context, concurrency int, timeout time.Duration) *HandlerLimiter {
+	var slots chan struct{}
+	if concurrency > 0 {
+		slots = make(chan struct{}, concurrency)
+	}
+
+	return &HandlerLimiter{
+		ctx:     ctx,
+		slots:   slots,
+		timeout: timeout,
+	}
+}
+
+// Middleware waits for a free slot and runs the next handler with deadline, see getUpdateContext.
+// Handlers waiting for a slot longer than the timeout are rejected.
+func (limiter *HandlerLimiter) Middleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		ctx, cancel := limiter.ctx, context.CancelFunc(func() {})
+		if limiter.timeout > 0 {
+			ctx, cancel = context.WithTimeout(limiter.ctx, limiter.timeout)
+		}
+
+		if !limiter.acquire(ctx) {
+			cancel()
+			HandlerQueueTimeoutCount.Inc()
+			return ErrHandlerQueueTimeout
+		}
+
+		c.Set(contextUpdateCtxKey, ctx)
+		done := make(chan error, 1)
+		go func() {
+			defer limiter.release()
+			defer cancel()
+			done <- next(c)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			// the handler could finish right before its context is cancelled
+			select {
+			case err := <-done:
+				return err
+			default:
+			}
+			HandlerTimeoutCount.Inc()
+			return ErrHandlerTimeout
+		}
+	}
+}
+
+func (limiter *HandlerLimiter) acquire(ctx context.Context) bool {
+	if limiter.slots == nil {
+		return true
+	}
+
+	HandlerQueueDepth.Inc()
+	defer HandlerQueueDepth.Dec()
+
+	select {
+	case limiter.slots <- struct{}{}:
+		HandlerInFlight.Inc()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (limiter *HandlerLimiter) release() {
+	if limiter.slots != nil {
+		<-limiter.slots
+		HandlerInFlight.Dec()
+	}
+}
+
+// getUpdateContext returns context with deadline of the update, or fallback when update is not limited.
+func getUpdateContext(c tele.Context, fallback context.Context) context.Context {
+	ctx, _ := c.Get(contextUpdateCtxKey).(context.Context)
+	if ctx == nil {
+		return fallback
+	}
+	return ctx
+}
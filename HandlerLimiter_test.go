@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
+	"testing"
+	"time"
+)
+
+func TestHandlerLimiter_Middleware(t *testing.T) {
+	bot := tele.Bot{}
+
+	t.Run("success", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+		limiter := NewHandlerLimiter(context.Background(), 1, time.Minute)
+
+		handler := limiter.Middleware(func(c tele.Context) error {
+			_, hasDeadline := getUpdateContext(c, nil).Deadline()
+			assert.True(t, hasDeadline)
+			return expectedError
+		})
+
+		assert.Equal(t, expectedError, handler(bot.NewContext(tele.Update{})))
+		assert.Equal(t, expectedError, handler(bot.NewContext(tele.Update{})))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		limiter := NewHandlerLimiter(context.Background(), 1, time.Millisecond*20)
+		timeoutCountBefore := HandlerTimeoutCount.Get()
+		release := make(chan struct{})
+
+		handler := limiter.Middleware(func(c tele.Context) error {
+			<-getUpdateContext(c, nil).Done()
+			<-release
+			return nil
+		})
+
+		assert.Equal(t, ErrHandlerTimeout, handler(bot.NewContext(tele.Update{})))
+		assert.Equal(t, timeoutCountBefore+1, HandlerTimeoutCount.Get())
+
+		// the slot is kept by the hung handler
+		queueTimeoutCountBefore := HandlerQueueTimeoutCount.Get()
+		assert.Equal(t, ErrHandlerQueueTimeout, handler(bot.NewContext(tele.Update{})))
+		assert.Equal(t, queueTimeoutCountBefore+1, HandlerQueueTimeoutCount.Get())
+
+		close(release)
+		assert.Eventually(t, func() bool {
+			return len(limiter.slots) == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("concurrency", func(t *testing.T) {
+		limiter := NewHandlerLimiter(context.Background(), 2, time.Second)
+		started := make(chan struct{}, 3)
+		release := make(chan struct{})
+
+		handler := limiter.Middleware(func(c tele.Context) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+
+		for i := 0; i < 3; i++ {
+			go func() {
+				_ = handler(bot.NewContext(tele.Update{}))
+			}()
+		}
+
+		<-started
+		<-started
+		assert.Eventually(t, func() bool {
+			return HandlerQueueDepth.Get() == 1
+		}, time.Second, time.Millisecond)
+		assert.Len(t, started, 0)
+
+		close(release)
+		<-started
+		assert.Eventually(t, func() bool {
+			return len(limiter.slots) == 0 && HandlerQueueDepth.Get() == 0
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		ctx := context.Background()
+		limiter := NewHandlerLimiter(ctx, 0, 0)
+
+		handler := limiter.Middleware(func(c tele.Context) error {
+			assert.Equal(t, ctx, getUpdateContext(c, nil))
+			return nil
+		})
+
+		assert.NoError(t, handler(bot.NewContext(tele.Update{})))
+	})
+}
+
+func TestGetUpdateContext(t *testing.T) {
+	bot := tele.Bot{}
+	fallback := context.Background()
+
+	assert.Equal(t, fallback, getUpdateContext(bot.NewContext(tele.Update{}), fallback))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"github.com/kneu-messenger-pigeon/client-framework/models"
+	"text/template"
+)
+
+//go:embed templates/*.md
+var helpTemplates embed.FS
+
+type HelpMessageData struct {
+	models.StudentMessageData
+	SupportInfo string
+}
+
+// HelpComposer renders /help from the templates like framework.MessageComposer does,
+// so the wording is changed in the templates only.
+type HelpComposer struct {
+	templates  *template.Template
+	postFilter func(string) string
+}
+
+func NewHelpComposer() *HelpComposer {
+	return &HelpComposer{
+		templates: template.Must(template.New("").ParseFS(helpTemplates, "templates/*.md")),
+		postFilter: func(text string) string {
+			return text
+		},
+	}
+}
+
+func (composer *HelpComposer) SetPostFilter(filter func(string) string) {
+	composer.postFilter = filter
+}
+
+func (composer *HelpComposer) ComposeHelpAnonymousMessage(messageData HelpMessageData) (error, string) {
+	return composer.compose("HelpAnonymous.md", messageData)
+}
+
+func (composer *HelpComposer) ComposeHelpAuthorizedMessage(messageData HelpMessageData) (error, string) {
+	return composer.compose("HelpAuthorized.md", messageData)
+}
+
+func (composer *HelpComposer) compose(name string, data any) (error, string) {
+	output := bytes.Buffer{}
+	err := composer.templates.ExecuteTemplate(&output, name, data)
+	return err, composer.postFilter(output.String())
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHelpComposer(t *testing.T) {
+	messageData := HelpMessageData{
+		StudentMessageData: markDownStudentMessageData(sampleStudent),
+		SupportInfo:        markDownData(SupportInfo),
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		composer := NewHelpComposer()
+		composer.SetPostFilter(formatMarkDown)
+
+		err, message := composer.ComposeHelpAnonymousMessage(HelpMessageData{SupportInfo: messageData.SupportInfo})
+
+		assert.NoError(t, err)
+		assert.Contains(t, message, "*Як увійти*")
+		assert.Contains(t, message, startCommand)
+		assert.Contains(t, message, SupportInfo)
+		assert.NotContains(t, message, sampleStudent.FirstName)
+	})
+
+	t.Run("authorized", func(t *testing.T) {
+		composer := NewHelpComposer()
+		composer.SetPostFilter(formatMarkDown)
+
+		err, message := composer.ComposeHelpAuthorizedMessage(messageData)
+
+		assert.NoError(t, err)
+		assert.Contains(t, message, sampleStudent.FirstName)
+		assert.Contains(t, message, listCommand+" \\- мої результати")
+		assert.Contains(t, message, resetCommand)
+		assert.Contains(t, message, "[офіційному журналі успішності КНЕУ](https://cutt.ly/Dekanat)")
+		assert.Contains(t, message, SupportInfo)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		composer := NewHelpComposer()
+		composer.SetPostFilter(formatHtml)
+
+		err, message := composer.ComposeHelpAuthorizedMessage(messageData)
+
+		assert.NoError(t, err)
+		assert.Contains(t, message, "<b>Команди</b>")
+		assert.Contains(t, message, `<a href="https://cutt.ly/Dekanat">`)
+	})
+}
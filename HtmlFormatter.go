@@ -0,0 +1,150 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// markDownHtmlTags maps template formatting to HTML tags of Telegram HTML style.
+// @see https://core.telegram.org/bots/api#html-style
+var markDownHtmlTags = map[string]string{
+	"*":  "b",
+	"_":  "i",
+	"__": "u",
+	"~":  "s",
+	"||": "tg-spoiler",
+}
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// formatHtml is a composer post filter converting markdown-ish templates to Telegram HTML.
+// Template text keeps the same formatting as for formatMarkDown, template and data text is HTML escaped.
+func formatHtml(markdownStr string) string {
+	tokens := tokenizeMarkDown(markdownStr)
+	output := strings.Builder{}
+	output.Grow(len(markdownStr) + len(markdownStr)/4)
+
+	context := markDownContextText
+	var openedTags []string
+	var linkText, linkUrl strings.Builder
+	inLinkText := false
+
+	// text of link is written after its url is known
+	write := func(text string) {
+		switch {
+		case context == markDownContextLinkUrl:
+			linkUrl.WriteString(text)
+		case inLinkText:
+			linkText.WriteString(htmlEscaper.Replace(text))
+		default:
+			output.WriteString(htmlEscaper.Replace(text))
+		}
+	}
+
+	writeTag := func(tag string) {
+		if inLinkText {
+			linkText.WriteString(tag)
+		} else {
+			output.WriteString(tag)
+		}
+	}
+
+	toggleTag := func(markup string) {
+		tag := markDownHtmlTags[markup]
+		for i := len(openedTags) - 1; i >= 0; i-- {
+			if openedTags[i] == tag {
+				openedTags = append(openedTags[:i], openedTags[i+1:]...)
+				writeTag("</" + tag + ">")
+				return
+			}
+		}
+
+		openedTags = append(openedTags, tag)
+		writeTag("<" + tag + ">")
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.isData {
+			write(string(token.char))
+			continue
+		}
+
+		switch {
+		case token.char == '\\' && context == markDownContextText && isMarkDownTemplateChar(tokens, i+1):
+			write(string(tokens[i+1].char))
+			i++
+
+		case context == markDownContextLinkUrl && token.char == ')':
+			context = markDownContextText
+			output.WriteString(`<a href="` + htmlEscaper.Replace(linkUrl.String()) + `">` + linkText.String() + "</a>")
+			linkText.Reset()
+			linkUrl.Reset()
+
+		case context == markDownContextCode && token.char == '`':
+			context = markDownContextText
+			writeTag("</code>")
+
+		case context == markDownContextPre && hasMarkDownTemplateSequence(tokens, i, "```"):
+			context = markDownContextText
+			writeTag("</pre>")
+			i += 2
+
+		case context != markDownContextText:
+			write(string(token.char))
+
+		case hasMarkDownTemplateSequence(tokens, i, "```"):
+			context = markDownContextPre
+			writeTag("<pre>")
+			i += 2
+
+		case token.char == '`':
+			context = markDownContextCode
+			writeTag("<code>")
+
+		case hasMarkDownTemplateSequence(tokens, i, "__"), hasMarkDownTemplateSequence(tokens, i, "||"):
+			toggleTag(string(token.char) + string(token.char))
+			i++
+
+		case strings.ContainsRune("*_~", token.char):
+			toggleTag(string(token.char))
+
+		case token.char == '[' && !inLinkText && hasMarkDownLinkEnd(tokens, i+1):
+			inLinkText = true
+
+		case token.char == ']' && inLinkText && hasMarkDownTemplateSequence(tokens, i, "]("):
+			inLinkText = false
+			context = markDownContextLinkUrl
+			i++
+
+		default:
+			write(string(token.char))
+		}
+	}
+
+	// close tags left open by template to keep the message valid
+	switch context {
+	case markDownContextCode:
+		writeTag("</code>")
+	case markDownContextPre:
+		writeTag("</pre>")
+	}
+	for i := len(openedTags) - 1; i >= 0; i-- {
+		output.WriteString("</" + openedTags[i] + ">")
+	}
+
+	return output.String()
+}
+
+var htmlLinkRegexp = regexp.MustCompile(`<a href="([^"]*)">(.*?)</a>`)
+var htmlTagRegexp = regexp.MustCompile(`</?[a-z-]+>`)
+
+// stripHtml converts text produced by formatHtml to plain text: tags are dropped,
+// entities are unescaped and inline links are rendered as "text (url)".
+func stripHtml(htmlStr string) string {
+	htmlStr = htmlLinkRegexp.ReplaceAllString(htmlStr, "$2 ($1)")
+	htmlStr = htmlTagRegexp.ReplaceAllString(htmlStr, "")
+
+	return html.UnescapeString(htmlStr)
+}
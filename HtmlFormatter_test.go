@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_FormatHtml(t *testing.T) {
+	t.Run("usual", func(t *testing.T) {
+		input := "Задачі ~прикладного~ системного аналізу: 50\nрейтинг #1/20 <b>."
+		expected := "Задачі <s>прикладного</s> системного аналізу: 50\nрейтинг #1/20 &lt;b&gt;."
+
+		assert.Equal(t, expected, formatHtml(input))
+	})
+
+	t.Run("formatting", func(t *testing.T) {
+		input := "*Бали*: _(було 1)_ __u__ ||spoiler|| `a*b` ```\nc_d\n``` 1\\*2"
+		expected := "<b>Бали</b>: <i>(було 1)</i> <u>u</u> <tg-spoiler>spoiler</tg-spoiler> " +
+			"<code>a*b</code> <pre>\nc_d\n</pre> 1*2"
+
+		assert.Equal(t, expected, formatHtml(input))
+	})
+
+	t.Run("links", func(t *testing.T) {
+		input := "ото таке [3 та 3].\n" +
+			"Перевіряйте оцінки в [*офіційному* журналі](https://cutt.ly/Dekanat?a=1&b=2)"
+		expected := "ото таке [3 та 3].\n" +
+			"Перевіряйте оцінки в <a href=\"https://cutt.ly/Dekanat?a=1&amp;b=2\"><b>офіційному</b> журналі</a>"
+
+		assert.Equal(t, expected, formatHtml(input))
+	})
+
+	t.Run("data", func(t *testing.T) {
+		input := "*" + markDownData("Капітал_2* <i>&") + "*: [" + markDownData("a](b") + "](" +
+			markDownData(`https://auth.test/?a="1"`) + ")"
+		expected := "<b>Капітал_2* &lt;i&gt;&amp;</b>: <a href=\"https://auth.test/?a=&quot;1&quot;\">a](b</a>"
+
+		assert.Equal(t, expected, formatHtml(input))
+	})
+
+	t.Run("unclosed", func(t *testing.T) {
+		assert.Equal(t, "<b>a <i>b</i></b>", formatHtml("*a _b"))
+		assert.Equal(t, "<code>a</code>", formatHtml("`a"))
+	})
+}
+
+func Test_StripHtml(t *testing.T) {
+	input := formatHtml("*Бали*: 3 & 4 [журнал](https://cutt.ly/Dekanat?a=1&b=2) " + markDownData("<i>"))
+	expected := "Бали: 3 & 4 журнал (https://cutt.ly/Dekanat?a=1&b=2) <i>"
+
+	assert.Equal(t, expected, stripHtml(input))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	tele "gopkg.in/telebot.v3"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const logFormatText = "text"
+
+const logFormatJson = "json"
+
+const contextUpdateStartKey = "updateStart"
+
+func newLogger(out io.Writer, format string) *slog.Logger {
+	if format == logFormatJson {
+		return slog.New(slog.NewJSONHandler(out, nil))
+	}
+
+	return slog.New(slog.NewTextHandler(out, nil))
+}
+
+// updateStartMiddleware remembers when processing of the update is started, so the log has its duration.
+func updateStartMiddleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		c.Set(contextUpdateStartKey, time.Now())
+		return next(c)
+	}
+}
+
+// updateLogAttrs describes the update: chat, student, update id, action and processing duration.
+func updateLogAttrs(c tele.Context) []any {
+	attrs := []any{slog.Int("update_id", c.Update().ID)}
+	if c.Chat() != nil {
+		attrs = append(attrs, slog.Int64("chat_id", c.Chat().ID))
+	}
+	if student := getStudent(c); student != nil {
+		attrs = append(attrs, slog.Uint64("student_id", uint64(student.Id)))
+	}
+	attrs = append(attrs, slog.String("action", updateAction(c)))
+
+	if start, ok := c.Get(contextUpdateStartKey).(time.Time); ok {
+		attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+	}
+
+	return attrs
+}
+
+// updateAction is a button unique of callback, a command or the type of update.
+func updateAction(c tele.Context) string {
+	switch {
+	case c.Callback() != nil:
+		return "callback:" + c.Callback().Unique
+	case strings.HasPrefix(c.Text(), "/"):
+		return strings.Fields(c.Text())[0]
+	case c.Message() != nil:
+		return "message"
+	}
+
+	return "update"
+}
+
+// errorLogAttrs adds Bot API error code when it is known, panic stack trace is logged as a separate field.
+func errorLogAttrs(err error) []any {
+	var attrs []any
+
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		attrs = append(attrs,
+			slog.String("error", fmt.Sprintf("panic: %v", panicErr.Value)),
+			slog.String("stack", string(panicErr.Stack)),
+		)
+	} else {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	if code := telegramErrorCode(err); code != 0 {
+		attrs = append(attrs, slog.Int("telegram_error_code", code))
+	}
+
+	return attrs
+}
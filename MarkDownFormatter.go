@@ -0,0 +1,224 @@
+package main
+
+import (
+	"github.com/kneu-messenger-pigeon/client-framework/models"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"strings"
+)
+
+// Data values are wrapped with private use runes before composing, so formatMarkDown could distinguish
+// formatting written in templates from discipline names, comments and other text received from users or API.
+const markDownDataStart = '\uE000'
+const markDownDataEnd = '\uE001'
+
+type markDownContext int
+
+const (
+	markDownContextText markDownContext = iota
+	markDownContextLinkUrl
+	markDownContextCode
+	markDownContextPre
+)
+
+// @see https://core.telegram.org/bots/api#markdownv2-style
+var markDownEscapeChars = map[markDownContext]string{
+	markDownContextText:    "_*[]()~`>#+-=|{}.!\\",
+	markDownContextLinkUrl: ")\\",
+	markDownContextCode:    "`\\",
+	markDownContextPre:     "`\\",
+}
+
+type markDownToken struct {
+	char   rune
+	isData bool
+}
+
+// markDownData marks text as data for formatMarkDown, empty text is kept empty for template conditions.
+func markDownData(text string) string {
+	if text == "" {
+		return text
+	}
+
+	text = strings.NewReplacer(string(markDownDataStart), "", string(markDownDataEnd), "").Replace(text)
+	return string(markDownDataStart) + text + string(markDownDataEnd)
+}
+
+// formatMarkDown is a composer post filter producing MarkdownV2 text.
+// Template text keeps bold, italic, underline, strikethrough, spoiler, inline links, code and pre entities,
+// other special chars are escaped according to the current entity; data values are always escaped.
+func formatMarkDown(markdownStr string) string {
+	tokens := tokenizeMarkDown(markdownStr)
+	output := strings.Builder{}
+	output.Grow(len(markdownStr) + len(markdownStr)/8)
+
+	context := markDownContextText
+	inLinkText := false
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.isData {
+			writeMarkDownEscaped(&output, token.char, context)
+			continue
+		}
+
+		switch {
+		case token.char == '\\' && context == markDownContextText && isMarkDownTemplateChar(tokens, i+1):
+			// escaped in template
+			output.WriteRune(token.char)
+			output.WriteRune(tokens[i+1].char)
+			i++
+
+		case context == markDownContextLinkUrl && token.char == ')':
+			context = markDownContextText
+			output.WriteRune(token.char)
+
+		case context == markDownContextCode && token.char == '`':
+			context = markDownContextText
+			output.WriteRune(token.char)
+
+		case context == markDownContextPre && hasMarkDownTemplateSequence(tokens, i, "```"):
+			context = markDownContextText
+			output.WriteString("```")
+			i += 2
+
+		case context != markDownContextText:
+			writeMarkDownEscaped(&output, token.char, context)
+
+		case hasMarkDownTemplateSequence(tokens, i, "```"):
+			context = markDownContextPre
+			output.WriteString("```")
+			i += 2
+
+		case token.char == '`':
+			context = markDownContextCode
+			output.WriteRune(token.char)
+
+		case strings.ContainsRune("*_~|", token.char):
+			output.WriteRune(token.char)
+
+		case token.char == '[' && !inLinkText && hasMarkDownLinkEnd(tokens, i+1):
+			inLinkText = true
+			output.WriteRune(token.char)
+
+		case token.char == ']' && inLinkText && hasMarkDownTemplateSequence(tokens, i, "]("):
+			inLinkText = false
+			context = markDownContextLinkUrl
+			output.WriteString("](")
+			i++
+
+		default:
+			writeMarkDownEscaped(&output, token.char, context)
+		}
+	}
+
+	return output.String()
+}
+
+func tokenizeMarkDown(markdownStr string) []markDownToken {
+	tokens := make([]markDownToken, 0, len(markdownStr))
+	dataDepth := 0
+
+	for _, char := range markdownStr {
+		switch char {
+		case markDownDataStart:
+			dataDepth++
+		case markDownDataEnd:
+			if dataDepth > 0 {
+				dataDepth--
+			}
+		default:
+			tokens = append(tokens, markDownToken{char: char, isData: dataDepth > 0})
+		}
+	}
+
+	return tokens
+}
+
+func writeMarkDownEscaped(output *strings.Builder, char rune, context markDownContext) {
+	if strings.ContainsRune(markDownEscapeChars[context], char) {
+		output.WriteRune('\\')
+	}
+	output.WriteRune(char)
+}
+
+func isMarkDownTemplateChar(tokens []markDownToken, i int) bool {
+	return i < len(tokens) && !tokens[i].isData
+}
+
+func hasMarkDownTemplateSequence(tokens []markDownToken, i int, sequence string) bool {
+	for _, char := range sequence {
+		if !isMarkDownTemplateChar(tokens, i) || tokens[i].char != char {
+			return false
+		}
+		i++
+	}
+
+	return true
+}
+
+// hasMarkDownLinkEnd checks that template text contains "](" and ")" on the same line.
+func hasMarkDownLinkEnd(tokens []markDownToken, i int) bool {
+	textClosed := false
+	for ; i < len(tokens); i++ {
+		if tokens[i].isData {
+			continue
+		}
+
+		switch {
+		case tokens[i].char == '\n' || (tokens[i].char == '[' && !textClosed):
+			return false
+		case !textClosed && hasMarkDownTemplateSequence(tokens, i, "]("):
+			textClosed = true
+			i++
+		case textClosed && tokens[i].char == ')':
+			return true
+		}
+	}
+
+	return false
+}
+
+func markDownStudentMessageData(student *models.Student) models.StudentMessageData {
+	studentMessageData := models.NewStudentMessageData(student)
+	studentMessageData.Name = markDownData(studentMessageData.Name)
+
+	return studentMessageData
+}
+
+func markDownDiscipline(discipline scoreApi.Discipline) scoreApi.Discipline {
+	discipline.Name = markDownData(discipline.Name)
+	return discipline
+}
+
+func markDownScore(score scoreApi.Score) scoreApi.Score {
+	score.Lesson.Type.ShortName = markDownData(score.Lesson.Type.ShortName)
+	score.Lesson.Type.LongName = markDownData(score.Lesson.Type.LongName)
+	return score
+}
+
+func markDownDisciplineScoreResult(discipline scoreApi.DisciplineScoreResult) scoreApi.DisciplineScoreResult {
+	discipline.Discipline = markDownDiscipline(discipline.Discipline)
+
+	scores := discipline.Scores
+	if scores != nil {
+		discipline.Scores = make([]scoreApi.Score, len(scores))
+		for i, score := range scores {
+			discipline.Scores[i] = markDownScore(score)
+		}
+	}
+
+	return discipline
+}
+
+func markDownDisciplineScoreResults(disciplines scoreApi.DisciplineScoreResults) scoreApi.DisciplineScoreResults {
+	if disciplines == nil {
+		return nil
+	}
+
+	output := make(scoreApi.DisciplineScoreResults, len(disciplines))
+	for i, discipline := range disciplines {
+		output[i] = markDownDisciplineScoreResult(discipline)
+	}
+
+	return output
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_FormatMarkDown(t *testing.T) {
+	t.Run("usual", func(t *testing.T) {
+		input := "Задачі ~прикладного~ системного аналізу: 50\nрейтинг #1/20."
+		expected := "Задачі ~прикладного~ системного аналізу: 50\nрейтинг \\#1/20\\."
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("brackets-with-leading-space", func(t *testing.T) {
+		input := "Бали: *3* _(було 1)_"
+		expected := "Бали: *3* _\\(було 1\\)_"
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("brackets-without-leading-space", func(t *testing.T) {
+		input := "Бали: 3(було 1)"
+		expected := "Бали: 3\\(було 1\\)"
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("links", func(t *testing.T) {
+		input := "Бали: 3 (було 1) ото таке [3 та 3].\n" +
+			"Перевіряйте оцінки в [офіційному журналі успішності КНЕУ](https://cutt.ly/Dekanat)"
+		expected := "Бали: 3 \\(було 1\\) ото таке \\[3 та 3\\]\\.\n" +
+			"Перевіряйте оцінки в [офіційному журналі успішності КНЕУ](https://cutt.ly/Dekanat)"
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("data", func(t *testing.T) {
+		input := "*" + markDownData("Капітал_2*") + "*: " + markDownData("[a](b) ~c~ ||d|| `e` \\") + "."
+		expected := "*Капітал\\_2\\**: \\[a\\]\\(b\\) \\~c\\~ \\|\\|d\\|\\| \\`e\\` \\\\\\."
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("data-link", func(t *testing.T) {
+		input := "[" + markDownData("пройти (авторизацію)]") + "](" + markDownData("https://auth.test/?a=(1)\\_.") + ")."
+		expected := "[пройти \\(авторизацію\\)\\]](https://auth.test/?a=(1\\)\\\\_.)\\."
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("data-brackets-are-not-link", func(t *testing.T) {
+		input := "[" + markDownData("](https://evil.test)") + "]"
+		expected := "\\[\\]\\(https://evil\\.test\\)\\]"
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("code-and-pre", func(t *testing.T) {
+		input := "`a.b_" + markDownData("`c\\*") + "` ```\nd.e_" + markDownData("```") + "\n```!"
+		expected := "`a.b_\\`c\\\\*` ```\nd.e_\\`\\`\\`\n```\\!"
+
+		assert.Equal(t, expected, formatMarkDown(input))
+	})
+
+	t.Run("template-escaped", func(t *testing.T) {
+		assert.Equal(t, "1\\*2\\.", formatMarkDown("1\\*2."))
+	})
+
+	t.Run("data-markers", func(t *testing.T) {
+		assert.Equal(t, "", markDownData(""))
+		assert.Equal(t, "a\\_b", formatMarkDown(markDownData("a"+string(markDownDataEnd)+"_b")))
+	})
+}
+
+func FuzzFormatMarkDown(f *testing.F) {
+	f.Add("Капітал_2*")
+	f.Add("[a](b)")
+	f.Add("` ``` \\ ) ||")
+	f.Add("__init__ ~x~ #1 > 2.5!")
+
+	f.Fuzz(func(t *testing.T, data string) {
+		if !utf8.ValidString(data) {
+			t.Skip()
+		}
+
+		template := "*" + markDownData(data) + "* _" + markDownData(data) + "_ ~" + markDownData(data) + "~ ||" +
+			markDownData(data) + "|| [" + markDownData(data) + "](" + markDownData("https://a.test/"+data) + ") `" +
+			markDownData(data) + "` ```\n" + markDownData(data) + "\n``` " + markDownData(data)
+
+		output := formatMarkDown(template)
+		assertBalancedMarkDown(t, output)
+
+		plain := strings.NewReplacer(string(markDownDataStart), "", string(markDownDataEnd), "").Replace(data)
+		assert.Equal(t, plain, stripMarkDown(formatMarkDown(markDownData(data))))
+	})
+}
+
+// assertBalancedMarkDown parses MarkdownV2 text and checks that every special char is escaped or
+// opens/closes an entity, entities are properly nested and closed at the end of the text.
+func assertBalancedMarkDown(t *testing.T, text string) {
+	chars := []rune(text)
+	var entities []string
+	context := markDownContextText
+
+	hasPrefix := func(i int, prefix string) bool {
+		return strings.HasPrefix(string(chars[i:]), prefix)
+	}
+
+	toggle := func(entity string) {
+		if len(entities) > 0 && entities[len(entities)-1] == entity {
+			entities = entities[:len(entities)-1]
+			return
+		}
+
+		for _, opened := range entities {
+			if opened == entity {
+				t.Fatalf("overlapped entity %q in %q", entity, text)
+			}
+		}
+		entities = append(entities, entity)
+	}
+
+	for i := 0; i < len(chars); i++ {
+		char := chars[i]
+		if char == '\\' {
+			if i+1 >= len(chars) || chars[i+1] < 1 || chars[i+1] > 126 {
+				t.Fatalf("invalid escape at %d in %q", i, text)
+			}
+			i++
+			continue
+		}
+
+		switch context {
+		case markDownContextLinkUrl:
+			if char == ')' {
+				context = markDownContextText
+			}
+			continue
+
+		case markDownContextCode:
+			if char == '`' {
+				context = markDownContextText
+			}
+			continue
+
+		case markDownContextPre:
+			if hasPrefix(i, "```") {
+				context = markDownContextText
+				i += 2
+			}
+			continue
+		}
+
+		switch {
+		case hasPrefix(i, "```"):
+			context = markDownContextPre
+			i += 2
+		case char == '`':
+			context = markDownContextCode
+		case hasPrefix(i, "||"):
+			toggle("||")
+			i++
+		case char == '*' || char == '_' || char == '~':
+			toggle(string(char))
+		case char == '[':
+			entities = append(entities, "[")
+		case hasPrefix(i, "]("):
+			if len(entities) == 0 || entities[len(entities)-1] != "[" {
+				t.Fatalf("unexpected link end at %d in %q", i, text)
+			}
+			entities = entities[:len(entities)-1]
+			context = markDownContextLinkUrl
+			i++
+		case strings.ContainsRune(markDownEscapeChars[markDownContextText], char):
+			t.Fatalf("unescaped %q at %d in %q", char, i, text)
+		}
+	}
+
+	if context != markDownContextText || len(entities) != 0 {
+		t.Fatalf("unclosed entities %v in %q", entities, text)
+	}
+}
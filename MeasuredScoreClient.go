@@ -0,0 +1,36 @@
+package main
+
+import (
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/kneu-messenger-pigeon/score-client"
+	"time"
+)
+
+// MeasuredScoreClient is a score.ClientInterface decorator observing latency of the score service requests.
+type MeasuredScoreClient struct {
+	client score.ClientInterface
+}
+
+func NewMeasuredScoreClient(client score.ClientInterface) *MeasuredScoreClient {
+	return &MeasuredScoreClient{
+		client: client,
+	}
+}
+
+func (measured *MeasuredScoreClient) GetStudentDisciplines(studentId uint32) (response scoreApi.DisciplineScoreResults, err error) {
+	defer observeScoreClientDuration("GetStudentDisciplines", time.Now(), &err)
+
+	return measured.client.GetStudentDisciplines(studentId)
+}
+
+func (measured *MeasuredScoreClient) GetStudentDiscipline(studentId uint32, disciplineId int) (response scoreApi.DisciplineScoreResult, err error) {
+	defer observeScoreClientDuration("GetStudentDiscipline", time.Now(), &err)
+
+	return measured.client.GetStudentDiscipline(studentId, disciplineId)
+}
+
+func (measured *MeasuredScoreClient) GetStudentScore(studentId uint32, disciplineId int, lessonId int) (response scoreApi.DisciplineScore, err error) {
+	defer observeScoreClientDuration("GetStudentScore", time.Now(), &err)
+
+	return measured.client.GetStudentScore(studentId, disciplineId, lessonId)
+}
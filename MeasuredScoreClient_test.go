@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"github.com/VictoriaMetrics/metrics"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	scoreMocks "github.com/kneu-messenger-pigeon/score-client/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMeasuredScoreClient(t *testing.T) {
+	studentId := uint32(999)
+	disciplineId := 199
+	expectedError := errors.New("expected error")
+	discipline := scoreApi.DisciplineScoreResult{Discipline: scoreApi.Discipline{Id: disciplineId}}
+
+	scoreClient := scoreMocks.NewClientInterface(t)
+	scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+	scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+
+	measured := NewMeasuredScoreClient(scoreClient)
+	successBefore := histogramCount(ScoreClientDurationHistogram("GetStudentDiscipline", nil))
+	errorBefore := histogramCount(ScoreClientDurationHistogram("GetStudentDisciplines", expectedError))
+
+	actual, err := measured.GetStudentDiscipline(studentId, disciplineId)
+	assert.NoError(t, err)
+	assert.Equal(t, discipline, actual)
+
+	_, err = measured.GetStudentDisciplines(studentId)
+	assert.Equal(t, expectedError, err)
+
+	assert.Equal(t, successBefore+1, histogramCount(ScoreClientDurationHistogram("GetStudentDiscipline", nil)))
+	assert.Equal(t, errorBefore+1, histogramCount(ScoreClientDurationHistogram("GetStudentDisciplines", expectedError)))
+}
+
+func histogramCount(histogram *metrics.Histogram) (count uint64) {
+	histogram.VisitNonZeroBuckets(func(_ string, bucketCount uint64) {
+		count += bucketCount
+	})
+	return count
+}
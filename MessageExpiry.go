@@ -0,0 +1,48 @@
+package main
+
+import (
+	"github.com/kneu-messenger-pigeon/client-framework/delayedDeleter/contracts"
+	"sync"
+)
+
+type messageExpiryKey struct {
+	chatId    int64
+	messageId int32
+}
+
+// MessageExpiry remembers prolonged lifetime of messages deleted by the delayed deleter, which could not cancel
+// already queued tasks, so earlier tasks of the message are skipped. It is kept in memory only,
+// so after restart the message is deleted by its first task.
+type MessageExpiry struct {
+	mutex    sync.Mutex
+	expireAt map[messageExpiryKey]int64
+}
+
+func NewMessageExpiry() *MessageExpiry {
+	return &MessageExpiry{
+		expireAt: make(map[messageExpiryKey]int64),
+	}
+}
+
+// Prolong sets new deletion time of the message, the task with this time should be queued by the caller.
+func (expiry *MessageExpiry) Prolong(chatId int64, messageId int, expireAt int64) {
+	expiry.mutex.Lock()
+	defer expiry.mutex.Unlock()
+
+	expiry.expireAt[messageExpiryKey{chatId: chatId, messageId: int32(messageId)}] = expireAt
+}
+
+// IsActual reports whether the task should delete the message, the message is forgotten by its actual task.
+func (expiry *MessageExpiry) IsActual(task *contracts.DeleteTask) bool {
+	expiry.mutex.Lock()
+	defer expiry.mutex.Unlock()
+
+	key := messageExpiryKey{chatId: task.GetChatId(), messageId: task.GetMessageId()}
+	expireAt, exists := expiry.expireAt[key]
+	if exists && task.GetScheduledAt() < expireAt {
+		return false
+	}
+
+	delete(expiry.expireAt, key)
+	return true
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/kneu-messenger-pigeon/client-framework/delayedDeleter/contracts"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMessageExpiry(t *testing.T) {
+	t.Run("not_prolonged", func(t *testing.T) {
+		expiry := NewMessageExpiry()
+
+		assert.True(t, expiry.IsActual(&contracts.DeleteTask{ScheduledAt: 100, MessageId: 1, ChatId: 2}))
+	})
+
+	t.Run("prolonged", func(t *testing.T) {
+		expiry := NewMessageExpiry()
+		expiry.Prolong(2, 1, 200)
+
+		assert.False(t, expiry.IsActual(&contracts.DeleteTask{ScheduledAt: 100, MessageId: 1, ChatId: 2}))
+		assert.True(t, expiry.IsActual(&contracts.DeleteTask{ScheduledAt: 100, MessageId: 1, ChatId: 3}))
+		assert.True(t, expiry.IsActual(&contracts.DeleteTask{ScheduledAt: 200, MessageId: 1, ChatId: 2}))
+
+		// the message is forgotten by its actual task
+		assert.Empty(t, expiry.expireAt)
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	tele "gopkg.in/telebot.v3"
+	"strings"
+)
+
+// telegramMessageLengthLimit is the maximum text length of a message in UTF-16 code units.
+// The limit is applied after entities parsing, so checking the formatted text is conservative.
+const telegramMessageLengthLimit = 4096
+
+// splitMessage splits formatted text into parts not longer than limit.
+// Parts are cut after a line break where all entities are closed, otherwise at any position where
+// entities are closed; an entity longer than the limit is cut as is.
+func splitMessage(text string, limit int, parseMode tele.ParseMode) []string {
+	chars := []rune(text)
+	if utf16Length(chars) <= limit {
+		return []string{text}
+	}
+
+	safe := entityBoundaries(chars, parseMode)
+
+	var parts []string
+	start := 0
+	for start < len(chars) {
+		end, length := start, 0
+		for end < len(chars) && length+utf16RuneLength(chars[end]) <= limit {
+			length += utf16RuneLength(chars[end])
+			end++
+		}
+
+		if end < len(chars) {
+			end = findMessageCut(chars, safe, start, end)
+		}
+
+		part := strings.Trim(string(chars[start:end]), "\n")
+		if part != "" {
+			parts = append(parts, part)
+		}
+		start = end
+	}
+
+	return parts
+}
+
+func findMessageCut(chars []rune, safe []bool, start int, end int) int {
+	for i := end; i > start; i-- {
+		if safe[i] && chars[i-1] == '\n' {
+			return i
+		}
+	}
+
+	for i := end; i > start; i-- {
+		if safe[i] {
+			return i
+		}
+	}
+
+	return end
+}
+
+// entityBoundaries reports for each position of text whether the text could be cut before it
+// without breaking an escape sequence, a tag or an entity.
+func entityBoundaries(chars []rune, parseMode tele.ParseMode) []bool {
+	switch parseMode {
+	case tele.ModeMarkdownV2:
+		return markDownEntityBoundaries(chars)
+	case tele.ModeHTML:
+		return htmlEntityBoundaries(chars)
+	}
+
+	safe := make([]bool, len(chars)+1)
+	for i := range safe {
+		safe[i] = true
+	}
+	return safe
+}
+
+func markDownEntityBoundaries(chars []rune) []bool {
+	safe := make([]bool, len(chars)+1)
+	opened := make(map[string]bool)
+	context := markDownContextText
+
+	isBalanced := func() bool {
+		if context != markDownContextText {
+			return false
+		}
+		for _, isOpened := range opened {
+			if isOpened {
+				return false
+			}
+		}
+		return true
+	}
+
+	hasPrefix := func(i int, prefix string) bool {
+		return strings.HasPrefix(string(chars[i:min(i+len(prefix), len(chars))]), prefix)
+	}
+
+	safe[0] = true
+	for i := 0; i < len(chars); i++ {
+		char := chars[i]
+
+		switch {
+		case char == '\\' && i+1 < len(chars):
+			i++
+
+		case context == markDownContextLinkUrl:
+			if char == ')' {
+				context = markDownContextText
+			}
+
+		case context == markDownContextCode:
+			if char == '`' {
+				context = markDownContextText
+			}
+
+		case context == markDownContextPre:
+			if hasPrefix(i, "```") {
+				context = markDownContextText
+				i += 2
+			}
+
+		case hasPrefix(i, "```"):
+			context = markDownContextPre
+			i += 2
+
+		case char == '`':
+			context = markDownContextCode
+
+		case hasPrefix(i, "__"), hasPrefix(i, "||"):
+			opened[string(chars[i:i+2])] = !opened[string(chars[i:i+2])]
+			i++
+
+		case strings.ContainsRune("*_~[", char):
+			opened[string(char)] = !opened[string(char)]
+
+		case hasPrefix(i, "]("):
+			opened["["] = false
+			context = markDownContextLinkUrl
+			i++
+		}
+
+		safe[i+1] = isBalanced()
+	}
+
+	return safe
+}
+
+func htmlEntityBoundaries(chars []rune) []bool {
+	safe := make([]bool, len(chars)+1)
+	depth := 0
+	inTag, inEntity, isClosingTag := false, false, false
+
+	safe[0] = true
+	for i, char := range chars {
+		switch {
+		case char == '<':
+			inTag = true
+			isClosingTag = i+1 < len(chars) && chars[i+1] == '/'
+		case inTag && char == '>':
+			inTag = false
+			if isClosingTag {
+				depth--
+			} else {
+				depth++
+			}
+		case char == '&':
+			inEntity = true
+		case inEntity && char == ';':
+			inEntity = false
+		}
+
+		safe[i+1] = depth == 0 && !inTag && !inEntity
+	}
+
+	return safe
+}
+
+func utf16Length(chars []rune) int {
+	length := 0
+	for _, char := range chars {
+		length += utf16RuneLength(char)
+	}
+	return length
+}
+
+func utf16RuneLength(char rune) int {
+	if char >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
+	"strings"
+	"testing"
+)
+
+func Test_SplitMessage(t *testing.T) {
+	t.Run("short", func(t *testing.T) {
+		assert.Equal(t, []string{"*a*\nb"}, splitMessage("*a*\nb", 10, tele.ModeMarkdownV2))
+	})
+
+	t.Run("lines", func(t *testing.T) {
+		text := "1111\n2222\n3333\n4444"
+		expected := []string{"1111\n2222", "3333\n4444"}
+
+		assert.Equal(t, expected, splitMessage(text, 12, tele.ModeMarkdownV2))
+	})
+
+	t.Run("markdown_entity_over_lines", func(t *testing.T) {
+		text := "11\n*22\n33*\n44"
+		expected := []string{"11", "*22\n33*\n44"}
+
+		assert.Equal(t, expected, splitMessage(text, 10, tele.ModeMarkdownV2))
+	})
+
+	t.Run("markdown_pre", func(t *testing.T) {
+		text := "1\n```\n*2\n3\n```\n4"
+		expected := []string{"1", "```\n*2\n3\n```", "4"}
+
+		assert.Equal(t, expected, splitMessage(text, 13, tele.ModeMarkdownV2))
+	})
+
+	t.Run("markdown_escape", func(t *testing.T) {
+		text := "aaa\\.bbb"
+		expected := []string{"aaa", "\\.bb", "b"}
+
+		assert.Equal(t, expected, splitMessage(text, 4, tele.ModeMarkdownV2))
+	})
+
+	t.Run("html", func(t *testing.T) {
+		text := "11\n<b>22\n33</b> &amp;\n44"
+		expected := []string{"11", "<b>22\n33</b> &amp;", "44"}
+
+		assert.Equal(t, expected, splitMessage(text, 19, tele.ModeHTML))
+	})
+
+	t.Run("long_line", func(t *testing.T) {
+		text := strings.Repeat("a", 10) + "\n" + strings.Repeat("b", 3)
+		expected := []string{"aaaa", "aaaa", "aa", "bbb"}
+
+		assert.Equal(t, expected, splitMessage(text, 4, tele.ModeDefault))
+	})
+
+	t.Run("utf16", func(t *testing.T) {
+		text := "😀😀\n😀"
+		expected := []string{"😀😀", "😀"}
+
+		assert.Equal(t, expected, splitMessage(text, 4, tele.ModeMarkdownV2))
+	})
+}
+
+func Test_WithoutReplyMarkup(t *testing.T) {
+	replyMarkup := &tele.ReplyMarkup{RemoveKeyboard: true}
+	sendOptions := &tele.SendOptions{ReplyMarkup: replyMarkup, Protected: true}
+
+	actual := withoutReplyMarkup([]interface{}{tele.Protected, replyMarkup, sendOptions})
+
+	assert.Equal(t, []interface{}{tele.Protected, &tele.SendOptions{Protected: true}}, actual)
+	assert.Equal(t, replyMarkup, sendOptions.ReplyMarkup)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const outboxCompactThreshold = 1000
+
+// OutboxEntry keeps arguments of ScoreChangedAction, so the notification could be replayed after restart.
+type OutboxEntry struct {
+	ChatId string `json:"chatId"`
+	// StudentId is used to save the message id of replayed notification, it is empty in entries of older versions
+	StudentId         uint32                   `json:"studentId,omitempty"`
+	PreviousMessageId string                   `json:"previousMessageId"`
+	DisciplineScore   scoreApi.DisciplineScore `json:"disciplineScore"`
+	PreviousScore     scoreApi.Score           `json:"previousScore"`
+	CreatedAt         time.Time                `json:"createdAt"`
+}
+
+func (entry *OutboxEntry) Key() string {
+	return fmt.Sprintf(
+		"%s:%d:%d",
+		entry.ChatId, entry.DisciplineScore.Discipline.Id, entry.DisciplineScore.Score.Lesson.Id,
+	)
+}
+
+type outboxRecord struct {
+	Key   string       `json:"key"`
+	Ack   bool         `json:"ack,omitempty"`
+	Entry *OutboxEntry `json:"entry,omitempty"`
+}
+
+// FileOutbox is an append-only log of pending notifications.
+// Each entry is written before the Telegram call and acknowledged after it,
+// entries with the same key (chatId, disciplineId, lessonId) replace each other.
+type FileOutbox struct {
+	mutex   sync.Mutex
+	path    string
+	file    *os.File
+	pending map[string]*OutboxEntry
+	records int
+}
+
+func OpenFileOutbox(path string) (*FileOutbox, error) {
+	outbox := &FileOutbox{
+		path:    path,
+		pending: make(map[string]*OutboxEntry),
+	}
+
+	err := outbox.load()
+	if err == nil {
+		err = outbox.compact()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox %s: %w", path, err)
+	}
+
+	return outbox, nil
+}
+
+func (outbox *FileOutbox) Put(entry *OutboxEntry) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	key := entry.Key()
+	err := outbox.append(outboxRecord{Key: key, Entry: entry})
+	if err == nil {
+		outbox.pending[key] = entry
+	}
+
+	return err
+}
+
+// Ack removes the entry, unless it is already replaced by a newer entry with the same key.
+func (outbox *FileOutbox) Ack(entry *OutboxEntry) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	key := entry.Key()
+	if outbox.pending[key] != entry {
+		return nil
+	}
+
+	err := outbox.append(outboxRecord{Key: key, Ack: true})
+	if err == nil {
+		delete(outbox.pending, key)
+	}
+
+	if err == nil && outbox.records > outboxCompactThreshold && outbox.records > len(outbox.pending)*2 {
+		err = outbox.compact()
+	}
+
+	return err
+}
+
+// IsPending reports whether the entry is neither acknowledged nor replaced by a newer entry with the same key.
+func (outbox *FileOutbox) IsPending(entry *OutboxEntry) bool {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	return outbox.pending[entry.Key()] == entry
+}
+
+// Pending returns not acknowledged entries, the oldest first.
+func (outbox *FileOutbox) Pending() []*OutboxEntry {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	entries := make([]*OutboxEntry, 0, len(outbox.pending))
+	for _, entry := range outbox.pending {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries
+}
+
+func (outbox *FileOutbox) Close() error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	return outbox.file.Close()
+}
+
+func (outbox *FileOutbox) load() error {
+	file, err := os.Open(outbox.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		record := outboxRecord{}
+		// the last line could be partially written on crash
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+
+		if record.Ack {
+			delete(outbox.pending, record.Key)
+		} else if record.Entry != nil {
+			outbox.pending[record.Key] = record.Entry
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact rewrites the log with pending entries only.
+func (outbox *FileOutbox) compact() error {
+	tmpPath := outbox.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(tmpFile)
+	encoder := json.NewEncoder(writer)
+	for key, entry := range outbox.pending {
+		err = encoder.Encode(outboxRecord{Key: key, Entry: entry})
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, outbox.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if outbox.file != nil {
+		_ = outbox.file.Close()
+	}
+
+	outbox.file, err = os.OpenFile(outbox.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	outbox.records = len(outbox.pending)
+
+	return err
+}
+
+func (outbox *FileOutbox) append(record outboxRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = outbox.file.Write(append(line, '\n'))
+	if err == nil {
+		err = outbox.file.Sync()
+	}
+	if err == nil {
+		outbox.records++
+	}
+
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func makeTestOutboxEntry(chatId string, disciplineId int, lessonId int, score float32) *OutboxEntry {
+	return &OutboxEntry{
+		ChatId: chatId,
+		DisciplineScore: scoreApi.DisciplineScore{
+			Discipline: scoreApi.Discipline{
+				Id:   disciplineId,
+				Name: "Капітал!",
+			},
+			Score: scoreApi.Score{
+				Lesson: scoreApi.Lesson{
+					Id:   lessonId,
+					Date: time.Date(2023, time.Month(2), 12, 0, 0, 0, 0, time.UTC),
+				},
+				FirstScore: floatPointer(score),
+			},
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestFileOutbox(t *testing.T) {
+	t.Run("put_ack_reopen", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.log")
+
+		outbox, err := OpenFileOutbox(path)
+		assert.NoError(t, err)
+
+		first := makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 2)
+		second := makeTestOutboxEntry(testTelegramUserIdString, 10, 101, 3)
+		third := makeTestOutboxEntry("555", 10, 100, 4)
+
+		assert.NoError(t, outbox.Put(first))
+		assert.NoError(t, outbox.Put(second))
+		assert.NoError(t, outbox.Put(third))
+		assert.NoError(t, outbox.Ack(second))
+		assert.NoError(t, outbox.Ack(makeTestOutboxEntry("not-exists", 10, 100, 2)))
+		assert.Len(t, outbox.Pending(), 2)
+		assert.NoError(t, outbox.Close())
+
+		outbox, err = OpenFileOutbox(path)
+		assert.NoError(t, err)
+		defer outbox.Close()
+
+		pending := outbox.Pending()
+		assert.Len(t, pending, 2)
+		assert.Equal(t, first.Key(), pending[0].Key())
+		assert.Equal(t, third.Key(), pending[1].Key())
+		assert.Equal(t, float32(2), *pending[0].DisciplineScore.Score.FirstScore)
+
+		// log is compacted on open
+		content, _ := os.ReadFile(path)
+		assert.Equal(t, 2, strings.Count(string(content), "\n"))
+	})
+
+	t.Run("deduplicate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.log")
+
+		outbox, err := OpenFileOutbox(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, outbox.Put(makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 2)))
+		assert.NoError(t, outbox.Put(makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 5)))
+		assert.NoError(t, outbox.Close())
+
+		outbox, err = OpenFileOutbox(path)
+		assert.NoError(t, err)
+		defer outbox.Close()
+
+		pending := outbox.Pending()
+		assert.Len(t, pending, 1)
+		assert.Equal(t, float32(5), *pending[0].DisciplineScore.Score.FirstScore)
+	})
+
+	t.Run("ack_replaced", func(t *testing.T) {
+		outbox, err := OpenFileOutbox(filepath.Join(t.TempDir(), "outbox.log"))
+		assert.NoError(t, err)
+		defer outbox.Close()
+
+		older := makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 2)
+		newer := makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 5)
+
+		assert.NoError(t, outbox.Put(older))
+		assert.NoError(t, outbox.Put(newer))
+		assert.False(t, outbox.IsPending(older))
+		assert.True(t, outbox.IsPending(newer))
+
+		assert.NoError(t, outbox.Ack(older))
+		assert.Len(t, outbox.Pending(), 1)
+
+		assert.NoError(t, outbox.Ack(newer))
+		assert.Empty(t, outbox.Pending())
+	})
+
+	t.Run("partially_written_line", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.log")
+
+		outbox, err := OpenFileOutbox(path)
+		assert.NoError(t, err)
+		assert.NoError(t, outbox.Put(makeTestOutboxEntry(testTelegramUserIdString, 10, 100, 2)))
+		assert.NoError(t, outbox.Close())
+
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		_, _ = file.WriteString(`{"key":"1238989:10:101","entry":{"chatId":`)
+		_ = file.Close()
+
+		outbox, err = OpenFileOutbox(path)
+		assert.NoError(t, err)
+		defer outbox.Close()
+
+		assert.Len(t, outbox.Pending(), 1)
+	})
+
+	t.Run("compact_on_ack", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.log")
+
+		outbox, err := OpenFileOutbox(path)
+		assert.NoError(t, err)
+		defer outbox.Close()
+
+		for i := 0; i < outboxCompactThreshold; i++ {
+			entry := makeTestOutboxEntry(testTelegramUserIdString, 10, i, 2)
+			assert.NoError(t, outbox.Put(entry))
+			assert.NoError(t, outbox.Ack(entry))
+		}
+
+		assert.Less(t, outbox.records, outboxCompactThreshold)
+		assert.Empty(t, outbox.Pending())
+	})
+
+	t.Run("wrong_path", func(t *testing.T) {
+		_, err := OpenFileOutbox(filepath.Join(t.TempDir(), "not-exists", "outbox.log"))
+		assert.Error(t, err)
+	})
+}
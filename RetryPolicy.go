@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	tele "gopkg.in/telebot.v3"
+	"io"
+	"math/rand/v2"
+	"net"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+type sendErrorClass int
+
+const (
+	sendErrorNone sendErrorClass = iota
+	sendErrorPermanent
+	sendErrorFlood
+	sendErrorTransient
+)
+
+func (class sendErrorClass) outcome() string {
+	switch class {
+	case sendErrorNone:
+		return "success"
+	case sendErrorFlood:
+		return "flood"
+	case sendErrorTransient:
+		return "transient"
+	}
+	return "permanent"
+}
+
+// telebot reports unknown API errors as plain `telegram: <description> (<code>)` strings
+var telegramErrorCodeRegexp = regexp.MustCompile(`\((\d{3})\)$`)
+
+type RetryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+func NewDefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		maxAttempts: sendRetryCount,
+		baseDelay:   time.Millisecond * 500,
+		maxDelay:    time.Second * 15,
+	}
+}
+
+// Backoff returns exponential delay for the given attempt (starting from 0) with "equal jitter":
+// half of the delay is fixed and another half is random.
+func (policy *RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := policy.maxDelay
+	if attempt < 30 && policy.baseDelay<<attempt < policy.maxDelay {
+		delay = policy.baseDelay << attempt
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + rand.N(half+1)
+}
+
+func classifySendError(err error) sendErrorClass {
+	if err == nil {
+		return sendErrorNone
+	}
+
+	if errors.As(err, &tele.FloodError{}) {
+		return sendErrorFlood
+	}
+
+	if isBlockedByUserErr(err) || errors.Is(err, ErrSendCancelled) || errors.Is(err, context.Canceled) {
+		return sendErrorPermanent
+	}
+
+	if telegramErrorCode(err) >= 500 {
+		return sendErrorTransient
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return sendErrorTransient
+	}
+
+	return sendErrorPermanent
+}
+
+// isUndeliveredSendErr reports whether the call was interrupted or failed temporarily,
+// so the message could be delivered by a later attempt.
+func isUndeliveredSendErr(err error) bool {
+	if errors.Is(err, ErrSendCancelled) {
+		return true
+	}
+
+	class := classifySendError(err)
+	return class == sendErrorFlood || class == sendErrorTransient
+}
+
+func telegramErrorCode(err error) int {
+	var botError *tele.Error
+	if errors.As(err, &botError) {
+		return botError.Code
+	}
+
+	match := telegramErrorCodeRegexp.FindStringSubmatch(err.Error())
+	if match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		maxAttempts: 5,
+		baseDelay:   time.Millisecond * 100,
+		maxDelay:    time.Second,
+	}
+
+	expectedDelays := []time.Duration{
+		time.Millisecond * 100,
+		time.Millisecond * 200,
+		time.Millisecond * 400,
+		time.Millisecond * 800,
+		time.Second,
+		time.Second,
+	}
+
+	for attempt, expectedDelay := range expectedDelays {
+		for i := 0; i < 20; i++ {
+			delay := policy.Backoff(attempt)
+			assert.GreaterOrEqual(t, delay, expectedDelay/2, "attempt %d", attempt)
+			assert.LessOrEqual(t, delay, expectedDelay, "attempt %d", attempt)
+		}
+	}
+
+	assert.LessOrEqual(t, policy.Backoff(100), time.Second)
+}
+
+func TestClassifySendError(t *testing.T) {
+	testCases := map[string]struct {
+		err      error
+		expected sendErrorClass
+	}{
+		"nil": {nil, sendErrorNone},
+		"flood": {
+			tele.FloodError{RetryAfter: 1},
+			sendErrorFlood,
+		},
+		"blocked": {tele.ErrBlockedByUser, sendErrorPermanent},
+		"badRequest": {
+			errors.New("telegram: Bad Request: can't parse entities (400)"),
+			sendErrorPermanent,
+		},
+		"knownTelegramError": {tele.ErrSameMessageContent, sendErrorPermanent},
+		"badGateway": {
+			errors.New("telegram: Bad Gateway (502)"),
+			sendErrorTransient,
+		},
+		"internal": {
+			tele.NewError(500, "Internal Server Error"),
+			sendErrorTransient,
+		},
+		"connectionReset": {
+			fmt.Errorf("telebot: %w", &url.Error{Op: "Post", URL: testTelegramURL, Err: syscall.ECONNRESET}),
+			sendErrorTransient,
+		},
+		"unexpectedEOF": {
+			fmt.Errorf("telebot: %w", io.ErrUnexpectedEOF),
+			sendErrorTransient,
+		},
+		"cancelled": {
+			fmt.Errorf("%w: %w", ErrSendCancelled, context.Canceled),
+			sendErrorPermanent,
+		},
+		"requestCancelled": {
+			fmt.Errorf("telebot: %w", &url.Error{Op: "Post", URL: testTelegramURL, Err: context.Canceled}),
+			sendErrorPermanent,
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, classifySendError(testCase.err))
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/kneu-messenger-pigeon/score-client"
+	"sync"
+	"time"
+)
+
+const defaultScoreCacheTTL = time.Minute
+
+// scoreCacheMaxStaleAge limits how long outdated data is served while the score service fails
+const scoreCacheMaxStaleAge = time.Hour
+
+type scoreCacheEntry[V any] struct {
+	value      V
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+type scoreCacheStore[K comparable, V any] struct {
+	mutex     sync.Mutex
+	entries   map[K]*scoreCacheEntry[V]
+	lastSweep time.Time
+	// generation is increased by invalidation, invalidated keeps the generation of the last invalidation of the key,
+	// so responses of the key requested before it are not stored; it is cleared when no request is in flight
+	generation  uint64
+	invalidated map[K]uint64
+	inFlight    int
+}
+
+type scoreCacheDisciplineKey struct {
+	studentId    uint32
+	disciplineId int
+}
+
+// ScoreCache is a score.ClientInterface decorator keeping student disciplines and discipline scores.
+// Fresh entries (younger than ttl) are returned as is. Outdated entries (younger than maxStaleAge) are returned
+// immediately and refreshed in background, so the student gets a response even when the score service fails.
+// Student scores of a single lesson are not cached.
+type ScoreCache struct {
+	client      score.ClientInterface
+	ttl         time.Duration
+	maxStaleAge time.Duration
+
+	disciplines *scoreCacheStore[uint32, scoreApi.DisciplineScoreResults]
+	discipline  *scoreCacheStore[scoreCacheDisciplineKey, scoreApi.DisciplineScoreResult]
+}
+
+func NewScoreCache(client score.ClientInterface, ttl time.Duration, maxStaleAge time.Duration) *ScoreCache {
+	return &ScoreCache{
+		client:      client,
+		ttl:         ttl,
+		maxStaleAge: max(ttl, maxStaleAge),
+		disciplines: newScoreCacheStore[uint32, scoreApi.DisciplineScoreResults](),
+		discipline:  newScoreCacheStore[scoreCacheDisciplineKey, scoreApi.DisciplineScoreResult](),
+	}
+}
+
+func newScoreCacheStore[K comparable, V any]() *scoreCacheStore[K, V] {
+	return &scoreCacheStore[K, V]{
+		entries:     make(map[K]*scoreCacheEntry[V]),
+		invalidated: make(map[K]uint64),
+		lastSweep:   time.Now(),
+	}
+}
+
+func (cache *ScoreCache) GetStudentDisciplines(studentId uint32) (scoreApi.DisciplineScoreResults, error) {
+	return getCached(cache, cache.disciplines, studentId, func() (scoreApi.DisciplineScoreResults, error) {
+		return cache.client.GetStudentDisciplines(studentId)
+	})
+}
+
+func (cache *ScoreCache) GetStudentDiscipline(studentId uint32, disciplineId int) (scoreApi.DisciplineScoreResult, error) {
+	key := scoreCacheDisciplineKey{studentId: studentId, disciplineId: disciplineId}
+
+	return getCached(cache, cache.discipline, key, func() (scoreApi.DisciplineScoreResult, error) {
+		return cache.client.GetStudentDiscipline(studentId, disciplineId)
+	})
+}
+
+func (cache *ScoreCache) GetStudentScore(studentId uint32, disciplineId int, lessonId int) (scoreApi.DisciplineScore, error) {
+	return cache.client.GetStudentScore(studentId, disciplineId, lessonId)
+}
+
+// Invalidate drops disciplines list of the student and scores of the changed discipline.
+func (cache *ScoreCache) Invalidate(studentId uint32, disciplineId int) {
+	cache.disciplines.delete(studentId)
+	cache.discipline.delete(scoreCacheDisciplineKey{studentId: studentId, disciplineId: disciplineId})
+	ScoreCacheInvalidatedTotal.Inc()
+}
+
+func getCached[K comparable, V any](
+	cache *ScoreCache, store *scoreCacheStore[K, V], key K, fetch func() (V, error),
+) (V, error) {
+	now := time.Now()
+
+	store.mutex.Lock()
+	if now.Sub(store.lastSweep) >= cache.maxStaleAge {
+		store.evictStale(now, cache.maxStaleAge)
+	}
+
+	entry, exists := store.entries[key]
+	if exists && now.Sub(entry.fetchedAt) < cache.ttl {
+		store.mutex.Unlock()
+		ScoreCacheHitTotal.Inc()
+		return entry.value, nil
+	}
+
+	if exists && now.Sub(entry.fetchedAt) < cache.maxStaleAge {
+		value := entry.value
+		startRefresh := !entry.refreshing
+		entry.refreshing = true
+		var generation uint64
+		if startRefresh {
+			generation = store.begin()
+		}
+		store.mutex.Unlock()
+
+		ScoreCacheStaleTotal.Inc()
+		if startRefresh {
+			go store.refresh(key, entry, generation, fetch)
+		}
+		return value, nil
+	}
+
+	generation := store.begin()
+	store.mutex.Unlock()
+
+	ScoreCacheMissTotal.Inc()
+	value, err := fetch()
+	store.finish(key, value, generation, err == nil)
+
+	return value, err
+}
+
+// refresh replaces the outdated entry, on error the entry is kept and served until maxStaleAge.
+func (store *scoreCacheStore[K, V]) refresh(key K, entry *scoreCacheEntry[V], generation uint64, fetch func() (V, error)) {
+	value, err := fetch()
+
+	store.mutex.Lock()
+	entry.refreshing = false
+	store.mutex.Unlock()
+
+	if err != nil {
+		ScoreCacheRefreshErrorCount.Inc()
+	}
+
+	store.finish(key, value, generation, err == nil)
+}
+
+// begin registers the request to the score service and returns the generation it is started at,
+// it should be called under the store mutex.
+func (store *scoreCacheStore[K, V]) begin() uint64 {
+	store.inFlight++
+	return store.generation
+}
+
+// finish stores the value unless the key is invalidated after the request is started.
+func (store *scoreCacheStore[K, V]) finish(key K, value V, generation uint64, success bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if success && store.invalidated[key] <= generation {
+		store.entries[key] = &scoreCacheEntry[V]{
+			value:     value,
+			fetchedAt: time.Now(),
+		}
+	}
+
+	store.inFlight--
+	if store.inFlight == 0 {
+		clear(store.invalidated)
+	}
+}
+
+func (store *scoreCacheStore[K, V]) delete(key K) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.entries, key)
+	if store.inFlight > 0 {
+		store.generation++
+		store.invalidated[key] = store.generation
+	}
+}
+
+func (store *scoreCacheStore[K, V]) evictStale(now time.Time, maxStaleAge time.Duration) {
+	for key, entry := range store.entries {
+		if now.Sub(entry.fetchedAt) >= maxStaleAge && !entry.refreshing {
+			delete(store.entries, key)
+		}
+	}
+	store.lastSweep = now
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	scoreMocks "github.com/kneu-messenger-pigeon/score-client/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestScoreCache(t *testing.T) {
+	studentId := uint32(999)
+	disciplineId := 199
+	expectedError := errors.New("expected error")
+
+	disciplines := scoreApi.DisciplineScoreResults{
+		{Discipline: scoreApi.Discipline{Id: disciplineId, Name: "Капітал!"}},
+	}
+	updatedDisciplines := scoreApi.DisciplineScoreResults{
+		{Discipline: scoreApi.Discipline{Id: disciplineId, Name: "Капітал!"}, ScoreRating: scoreApi.ScoreRating{Total: 5}},
+	}
+	discipline := scoreApi.DisciplineScoreResult{Discipline: scoreApi.Discipline{Id: disciplineId}}
+
+	t.Run("fresh", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+
+		cache := NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		for i := 0; i < 2; i++ {
+			actualDisciplines, err := cache.GetStudentDisciplines(studentId)
+			assert.NoError(t, err)
+			assert.Equal(t, disciplines, actualDisciplines)
+
+			actualDiscipline, err := cache.GetStudentDiscipline(studentId, disciplineId)
+			assert.NoError(t, err)
+			assert.Equal(t, discipline, actualDiscipline)
+		}
+	})
+
+	t.Run("miss_error", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once()
+
+		cache := NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		_, err := cache.GetStudentDisciplines(studentId)
+		assert.Equal(t, expectedError, err)
+
+		actual, err := cache.GetStudentDisciplines(studentId)
+		assert.NoError(t, err)
+		assert.Equal(t, disciplines, actual)
+	})
+
+	t.Run("stale_while_revalidate", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(updatedDisciplines, nil)
+
+		cache := NewScoreCache(scoreClient, time.Millisecond*20, time.Hour)
+
+		_, _ = cache.GetStudentDisciplines(studentId)
+		time.Sleep(time.Millisecond * 30)
+
+		actual, err := cache.GetStudentDisciplines(studentId)
+		assert.NoError(t, err)
+		assert.Equal(t, disciplines, actual)
+
+		assert.Eventually(t, func() bool {
+			actual, _ = cache.GetStudentDisciplines(studentId)
+			return actual[0].ScoreRating.Total == 5
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("stale_on_error", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(scoreApi.DisciplineScoreResult{}, expectedError)
+
+		cache := NewScoreCache(scoreClient, time.Millisecond, time.Hour)
+		errorsBefore := ScoreCacheRefreshErrorCount.Get()
+
+		_, _ = cache.GetStudentDiscipline(studentId, disciplineId)
+
+		for i := 0; i < 3; i++ {
+			time.Sleep(time.Millisecond * 5)
+			actual, err := cache.GetStudentDiscipline(studentId, disciplineId)
+			assert.NoError(t, err)
+			assert.Equal(t, discipline, actual)
+		}
+
+		assert.Eventually(t, func() bool {
+			return ScoreCacheRefreshErrorCount.Get() >= errorsBefore+1
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+
+		cache := NewScoreCache(scoreClient, time.Millisecond, time.Millisecond*10)
+
+		_, _ = cache.GetStudentDisciplines(studentId)
+		time.Sleep(time.Millisecond * 15)
+
+		_, err := cache.GetStudentDisciplines(studentId)
+		assert.Equal(t, expectedError, err)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(updatedDisciplines, nil).Once()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Twice()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId+1).Return(discipline, nil).Once()
+
+		cache := NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		_, _ = cache.GetStudentDisciplines(studentId)
+		_, _ = cache.GetStudentDiscipline(studentId, disciplineId)
+		_, _ = cache.GetStudentDiscipline(studentId, disciplineId+1)
+
+		cache.Invalidate(studentId, disciplineId)
+
+		actual, err := cache.GetStudentDisciplines(studentId)
+		assert.NoError(t, err)
+		assert.Equal(t, updatedDisciplines, actual)
+
+		_, _ = cache.GetStudentDiscipline(studentId, disciplineId)
+		_, _ = cache.GetStudentDiscipline(studentId, disciplineId+1)
+	})
+
+	t.Run("invalidate_in_flight", func(t *testing.T) {
+		otherStudentId := studentId + 1
+
+		scoreClient := scoreMocks.NewClientInterface(t)
+		cache := NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		// the response requested before invalidation of the same student is not stored
+		scoreClient.On("GetStudentDisciplines", studentId).Return(disciplines, nil).Once().Run(func(mock.Arguments) {
+			cache.Invalidate(studentId, disciplineId)
+		})
+		scoreClient.On("GetStudentDisciplines", studentId).Return(updatedDisciplines, nil).Once()
+
+		// invalidation of another student does not affect the response
+		scoreClient.On("GetStudentDisciplines", otherStudentId).Return(disciplines, nil).Once().Run(func(mock.Arguments) {
+			cache.Invalidate(studentId, disciplineId)
+		})
+
+		actual, err := cache.GetStudentDisciplines(studentId)
+		assert.NoError(t, err)
+		assert.Equal(t, disciplines, actual)
+
+		actual, err = cache.GetStudentDisciplines(studentId)
+		assert.NoError(t, err)
+		assert.Equal(t, updatedDisciplines, actual)
+
+		for i := 0; i < 2; i++ {
+			actual, err = cache.GetStudentDisciplines(otherStudentId)
+			assert.NoError(t, err)
+			assert.Equal(t, disciplines, actual)
+		}
+
+		assert.Empty(t, cache.disciplines.invalidated)
+	})
+
+	t.Run("score_not_cached", func(t *testing.T) {
+		score := scoreApi.DisciplineScore{Discipline: discipline.Discipline}
+
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentScore", studentId, disciplineId, 15).Return(score, nil).Twice()
+
+		cache := NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		for i := 0; i < 2; i++ {
+			actual, err := cache.GetStudentScore(studentId, disciplineId, 15)
+			assert.NoError(t, err)
+			assert.Equal(t, score, actual)
+		}
+	})
+}
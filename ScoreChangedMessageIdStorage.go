@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"github.com/kneu-messenger-pigeon/client-framework/models"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// ScoreChangedMessageIdStorage shares the redis keys of framework.ScoreChangedMessageIdStorage,
+// so message ids of replayed notifications are used by the framework for the next changes of the score.
+// The framework (v0.1.53) neither exports its storage with the redis client nor a constructor for them,
+// so the key format should be kept in sync with it.
+type ScoreChangedMessageIdStorage struct {
+	logger        *slog.Logger
+	redis         redis.UniversalClient
+	storageExpire time.Duration
+}
+
+func NewScoreChangedMessageIdStorage(
+	logger *slog.Logger, redisClient redis.UniversalClient, storageExpire time.Duration,
+) *ScoreChangedMessageIdStorage {
+	return &ScoreChangedMessageIdStorage{
+		logger:        logger,
+		redis:         redisClient,
+		storageExpire: storageExpire,
+	}
+}
+
+func (storage *ScoreChangedMessageIdStorage) Set(studentId uint, lessonId uint, chatId string, messageId string) {
+	key := storage.makeKey(studentId, lessonId)
+	var err error
+	if messageId == "" {
+		err = storage.redis.HDel(context.Background(), key, chatId).Err()
+	} else {
+		err = storage.redis.HSet(context.Background(), key, chatId, messageId).Err()
+	}
+
+	if err == nil {
+		err = storage.redis.Expire(context.Background(), key, storage.storageExpire).Err()
+	}
+
+	if err != nil {
+		storage.logger.With(errorLogAttrs(err)...).Error(
+			"Failed to save score changed message id",
+			slog.Uint64("student_id", uint64(studentId)),
+			slog.Uint64("lesson_id", uint64(lessonId)),
+			slog.String("chat_id", chatId),
+			slog.String("message_id", messageId),
+		)
+	}
+}
+
+func (storage *ScoreChangedMessageIdStorage) GetAll(studentId uint, lessonId uint) models.ScoreChangedMessageMap {
+	result := storage.redis.HGetAll(context.Background(), storage.makeKey(studentId, lessonId))
+	if result.Err() == nil {
+		return result.Val()
+	}
+
+	return models.ScoreChangedMessageMap{}
+}
+
+func (storage *ScoreChangedMessageIdStorage) makeKey(studentId uint, lessonId uint) string {
+	return "SM" + strconv.FormatUint(uint64(studentId), 10) + ":" + strconv.FormatUint(uint64(lessonId), 10)
+}
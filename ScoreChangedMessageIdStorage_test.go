@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"github.com/go-redis/redismock/v9"
+	"github.com/kneu-messenger-pigeon/client-framework/models"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestScoreChangedMessageIdStorage_Set(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+
+		storage := NewScoreChangedMessageIdStorage(slog.New(slog.NewTextHandler(io.Discard, nil)), redisClient, time.Minute)
+
+		redisMock.ExpectHSet("SM123:99", testTelegramUserIdString, "456").SetVal(1)
+		redisMock.ExpectExpire("SM123:99", time.Minute).SetVal(true)
+
+		storage.Set(123, 99, testTelegramUserIdString, "456")
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("set_empty", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+
+		storage := NewScoreChangedMessageIdStorage(slog.New(slog.NewTextHandler(io.Discard, nil)), redisClient, time.Minute)
+
+		redisMock.ExpectHDel("SM123:99", testTelegramUserIdString).SetVal(1)
+		redisMock.ExpectExpire("SM123:99", time.Minute).SetVal(true)
+
+		storage.Set(123, 99, testTelegramUserIdString, "")
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		redisClient, redisMock := redismock.NewClientMock()
+		out := &bytes.Buffer{}
+		storage := NewScoreChangedMessageIdStorage(slog.New(slog.NewJSONHandler(out, nil)), redisClient, time.Minute)
+
+		redisMock.ExpectHSet("SM123:99", testTelegramUserIdString, "456").SetErr(expectedError)
+
+		storage.Set(123, 99, testTelegramUserIdString, "456")
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `"msg":"Failed to save score changed message id"`)
+		assert.Contains(t, out.String(), `"error":"expected error"`)
+		assert.Contains(t, out.String(), `"student_id":123`)
+	})
+}
+
+func TestScoreChangedMessageIdStorage_GetAll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		storage := NewScoreChangedMessageIdStorage(slog.New(slog.NewTextHandler(io.Discard, nil)), redisClient, time.Minute)
+
+		redisMock.ExpectHGetAll("SM123:99").SetVal(map[string]string{testTelegramUserIdString: "456"})
+
+		assert.Equal(t, models.ScoreChangedMessageMap{testTelegramUserIdString: "456"}, storage.GetAll(123, 99))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		storage := NewScoreChangedMessageIdStorage(slog.New(slog.NewTextHandler(io.Discard, nil)), redisClient, time.Minute)
+
+		redisMock.ExpectHGetAll("SM123:99").SetErr(errors.New("expected error"))
+
+		assert.Equal(t, models.ScoreChangedMessageMap{}, storage.GetAll(123, 99))
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/kneu-messenger-pigeon/score-client"
+	"sync"
+	"time"
+)
+
+const scoreCircuitBreakerFailureThreshold = 5
+
+const scoreCircuitBreakerOpenTimeout = time.Second * 30
+
+// circuitState values are exposed as score_circuit_breaker_state metric
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+var ErrScoreCircuitOpen = errors.New("score service circuit breaker is open")
+
+// ScoreCircuitBreaker is a score.ClientInterface decorator, which stops calling the score service after
+// failureThreshold consecutive errors. After openTimeout a single probe call is allowed:
+// its success closes the circuit, its error opens it again.
+type ScoreCircuitBreaker struct {
+	client           score.ClientInterface
+	failureThreshold int
+	openTimeout      time.Duration
+
+	mutex         sync.Mutex
+	state         circuitState
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+func NewScoreCircuitBreaker(client score.ClientInterface, failureThreshold int, openTimeout time.Duration) *ScoreCircuitBreaker {
+	ScoreCircuitBreakerState.Set(float64(circuitClosed))
+
+	return &ScoreCircuitBreaker{
+		client:           client,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+	}
+}
+
+func NewDefaultScoreCircuitBreaker(client score.ClientInterface) *ScoreCircuitBreaker {
+	return NewScoreCircuitBreaker(client, scoreCircuitBreakerFailureThreshold, scoreCircuitBreakerOpenTimeout)
+}
+
+func (breaker *ScoreCircuitBreaker) GetStudentDisciplines(studentId uint32) (scoreApi.DisciplineScoreResults, error) {
+	return callWithCircuitBreaker(breaker, func() (scoreApi.DisciplineScoreResults, error) {
+		return breaker.client.GetStudentDisciplines(studentId)
+	})
+}
+
+func (breaker *ScoreCircuitBreaker) GetStudentDiscipline(studentId uint32, disciplineId int) (scoreApi.DisciplineScoreResult, error) {
+	return callWithCircuitBreaker(breaker, func() (scoreApi.DisciplineScoreResult, error) {
+		return breaker.client.GetStudentDiscipline(studentId, disciplineId)
+	})
+}
+
+func (breaker *ScoreCircuitBreaker) GetStudentScore(studentId uint32, disciplineId int, lessonId int) (scoreApi.DisciplineScore, error) {
+	return callWithCircuitBreaker(breaker, func() (scoreApi.DisciplineScore, error) {
+		return breaker.client.GetStudentScore(studentId, disciplineId, lessonId)
+	})
+}
+
+// State reports the circuit half-open once openTimeout is passed, even if no call was made since then,
+// so readiness is restored while no requests are coming.
+func (breaker *ScoreCircuitBreaker) State() circuitState {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.halfOpenAfterTimeout(time.Now())
+	return breaker.state
+}
+
+func callWithCircuitBreaker[T any](breaker *ScoreCircuitBreaker, call func() (T, error)) (response T, err error) {
+	if !breaker.allow(time.Now()) {
+		ScoreCircuitBreakerRejectedTotal.Inc()
+		return response, ErrScoreCircuitOpen
+	}
+
+	response, err = call()
+	breaker.record(err, time.Now())
+
+	return response, err
+}
+
+func (breaker *ScoreCircuitBreaker) allow(now time.Time) bool {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.halfOpenAfterTimeout(now)
+
+	switch breaker.state {
+	case circuitOpen:
+		return false
+
+	case circuitHalfOpen:
+		// other calls are rejected until the probe call is finished
+		if breaker.probeInFlight {
+			return false
+		}
+		breaker.probeInFlight = true
+	}
+
+	return true
+}
+
+func (breaker *ScoreCircuitBreaker) record(err error, now time.Time) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	breaker.probeInFlight = false
+	if err == nil {
+		breaker.failures = 0
+		breaker.setState(circuitClosed)
+		return
+	}
+
+	breaker.failures++
+	if breaker.state == circuitHalfOpen || breaker.failures >= breaker.failureThreshold {
+		breaker.openedAt = now
+		breaker.setState(circuitOpen)
+	}
+}
+
+func (breaker *ScoreCircuitBreaker) halfOpenAfterTimeout(now time.Time) {
+	if breaker.state == circuitOpen && now.Sub(breaker.openedAt) >= breaker.openTimeout {
+		breaker.setState(circuitHalfOpen)
+	}
+}
+
+func (breaker *ScoreCircuitBreaker) setState(state circuitState) {
+	breaker.state = state
+	ScoreCircuitBreakerState.Set(float64(state))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	scoreMocks "github.com/kneu-messenger-pigeon/score-client/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestScoreCircuitBreaker(t *testing.T) {
+	studentId := uint32(999)
+	disciplineId := 199
+	expectedError := errors.New("expected error")
+	discipline := scoreApi.DisciplineScoreResult{Discipline: scoreApi.Discipline{Id: disciplineId}}
+
+	t.Run("open_after_failures", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Times(2)
+
+		breaker := NewScoreCircuitBreaker(scoreClient, 2, time.Minute)
+		rejectedBefore := ScoreCircuitBreakerRejectedTotal.Get()
+
+		_, err := breaker.GetStudentDisciplines(studentId)
+		assert.Equal(t, expectedError, err)
+		assert.Equal(t, circuitClosed, breaker.State())
+
+		_, err = breaker.GetStudentDisciplines(studentId)
+		assert.Equal(t, expectedError, err)
+		assert.Equal(t, circuitOpen, breaker.State())
+		assert.Equal(t, float64(circuitOpen), ScoreCircuitBreakerState.Get())
+
+		_, err = breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.Equal(t, ErrScoreCircuitOpen, err)
+		assert.Equal(t, rejectedBefore+1, ScoreCircuitBreakerRejectedTotal.Get())
+	})
+
+	t.Run("success_resets_failures", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+
+		breaker := NewScoreCircuitBreaker(scoreClient, 2, time.Minute)
+
+		_, _ = breaker.GetStudentDisciplines(studentId)
+		actual, err := breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.NoError(t, err)
+		assert.Equal(t, discipline, actual)
+
+		_, _ = breaker.GetStudentDisciplines(studentId)
+		assert.Equal(t, circuitClosed, breaker.State())
+	})
+
+	t.Run("half_open_probe", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(scoreApi.DisciplineScoreResult{}, expectedError).Twice()
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+
+		breaker := NewScoreCircuitBreaker(scoreClient, 1, time.Millisecond*50)
+
+		_, err := breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.Equal(t, expectedError, err)
+		assert.Equal(t, circuitOpen, breaker.State())
+
+		// failed probe opens the circuit again
+		time.Sleep(time.Millisecond * 60)
+		_, err = breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.Equal(t, expectedError, err)
+		_, err = breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.Equal(t, ErrScoreCircuitOpen, err)
+
+		time.Sleep(time.Millisecond * 60)
+		actual, err := breaker.GetStudentDiscipline(studentId, disciplineId)
+		assert.NoError(t, err)
+		assert.Equal(t, discipline, actual)
+		assert.Equal(t, circuitClosed, breaker.State())
+		assert.Equal(t, float64(circuitClosed), ScoreCircuitBreakerState.Get())
+	})
+
+	t.Run("half_open_after_timeout_without_calls", func(t *testing.T) {
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+
+		breaker := NewScoreCircuitBreaker(scoreClient, 1, time.Millisecond*50)
+
+		_, _ = breaker.GetStudentDisciplines(studentId)
+		assert.Equal(t, circuitOpen, breaker.State())
+
+		time.Sleep(time.Millisecond * 60)
+		assert.Equal(t, circuitHalfOpen, breaker.State())
+		assert.Equal(t, float64(circuitHalfOpen), ScoreCircuitBreakerState.Get())
+	})
+
+	t.Run("single_probe_in_half_open", func(t *testing.T) {
+		breaker := NewScoreCircuitBreaker(scoreMocks.NewClientInterface(t), 1, time.Millisecond)
+		breaker.record(expectedError, time.Now().Add(-time.Second))
+
+		assert.True(t, breaker.allow(time.Now()))
+		assert.Equal(t, circuitHalfOpen, breaker.State())
+		assert.False(t, breaker.allow(time.Now()))
+
+		breaker.record(nil, time.Now())
+		assert.True(t, breaker.allow(time.Now()))
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+const senderThrottleBurst = 5
+
+const senderThrottleIdleTTL = time.Minute * 5
+
+// repeated taps on the same button within the window are processed once
+const senderThrottleDuplicateWindow = time.Second * 2
+
+// the user is told about throttling not more often than once per the interval, other excess updates are dropped
+const senderThrottleNoticeInterval = time.Second * 10
+
+type throttleDecision int
+
+const (
+	throttleAllow throttleDecision = iota
+	throttleDuplicate
+	throttleReject
+	throttleDrop
+)
+
+type senderThrottleEntry struct {
+	limiter        *rate.Limiter
+	lastUsed       time.Time
+	lastCallback   string
+	lastCallbackAt time.Time
+	lastNoticeAt   time.Time
+}
+
+// SenderThrottle keeps a token bucket for each sender of updates and remembers the last callback of the sender.
+type SenderThrottle struct {
+	limit           rate.Limit
+	burst           int
+	idleTTL         time.Duration
+	duplicateWindow time.Duration
+	noticeInterval  time.Duration
+
+	mutex     sync.Mutex
+	entries   map[int64]*senderThrottleEntry
+	lastSweep time.Time
+}
+
+func NewSenderThrottle(
+	limit rate.Limit, burst int, idleTTL time.Duration, duplicateWindow time.Duration, noticeInterval time.Duration,
+) *SenderThrottle {
+	return &SenderThrottle{
+		limit:           limit,
+		burst:           burst,
+		idleTTL:         idleTTL,
+		duplicateWindow: duplicateWindow,
+		noticeInterval:  noticeInterval,
+		entries:         make(map[int64]*senderThrottleEntry),
+		lastSweep:       time.Now(),
+	}
+}
+
+func NewDefaultSenderThrottle() *SenderThrottle {
+	return NewSenderThrottle(
+		rate.Every(time.Second), senderThrottleBurst, senderThrottleIdleTTL,
+		senderThrottleDuplicateWindow, senderThrottleNoticeInterval,
+	)
+}
+
+// Check decides whether the update of the sender should be processed.
+// callback is the unique and data of pressed button, empty for other updates.
+func (throttle *SenderThrottle) Check(senderId int64, callback string, now time.Time) throttleDecision {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	if now.Sub(throttle.lastSweep) >= throttle.idleTTL {
+		throttle.evictIdle(now)
+	}
+
+	entry, exists := throttle.entries[senderId]
+	if !exists {
+		entry = &senderThrottleEntry{
+			limiter: rate.NewLimiter(throttle.limit, throttle.burst),
+		}
+		throttle.entries[senderId] = entry
+	}
+	entry.lastUsed = now
+
+	if callback != "" {
+		if callback == entry.lastCallback && now.Sub(entry.lastCallbackAt) < throttle.duplicateWindow {
+			return throttleDuplicate
+		}
+		entry.lastCallback = callback
+		entry.lastCallbackAt = now
+	}
+
+	if entry.limiter.AllowN(now, 1) {
+		return throttleAllow
+	}
+
+	if now.Sub(entry.lastNoticeAt) >= throttle.noticeInterval {
+		entry.lastNoticeAt = now
+		return throttleReject
+	}
+
+	return throttleDrop
+}
+
+func (throttle *SenderThrottle) Len() int {
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	return len(throttle.entries)
+}
+
+func (throttle *SenderThrottle) evictIdle(now time.Time) {
+	for senderId, entry := range throttle.entries {
+		if now.Sub(entry.lastUsed) >= throttle.idleTTL {
+			delete(throttle.entries, senderId)
+		}
+	}
+	throttle.lastSweep = now
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"testing"
+	"time"
+)
+
+func TestSenderThrottle_Check(t *testing.T) {
+	t.Run("rate", func(t *testing.T) {
+		throttle := NewSenderThrottle(rate.Every(time.Second), 2, time.Minute, time.Second, time.Second*10)
+		now := time.Now()
+
+		assert.Equal(t, throttleAllow, throttle.Check(1, "", now))
+		assert.Equal(t, throttleAllow, throttle.Check(1, "", now))
+		assert.Equal(t, throttleReject, throttle.Check(1, "", now))
+		assert.Equal(t, throttleDrop, throttle.Check(1, "", now))
+		assert.Equal(t, throttleAllow, throttle.Check(2, "", now))
+
+		assert.Equal(t, throttleAllow, throttle.Check(1, "", now.Add(time.Second)))
+		assert.Equal(t, throttleDrop, throttle.Check(1, "", now.Add(time.Second)))
+
+		later := now.Add(time.Second * 10)
+		assert.Equal(t, throttleAllow, throttle.Check(1, "", later))
+		assert.Equal(t, throttleAllow, throttle.Check(1, "", later))
+		assert.Equal(t, throttleReject, throttle.Check(1, "", later))
+	})
+
+	t.Run("duplicate_callback", func(t *testing.T) {
+		throttle := NewSenderThrottle(rate.Inf, 1, time.Minute, time.Second, time.Second*10)
+		now := time.Now()
+
+		assert.Equal(t, throttleAllow, throttle.Check(1, "discipline|1.a.sig", now))
+		assert.Equal(t, throttleDuplicate, throttle.Check(1, "discipline|1.a.sig", now.Add(time.Millisecond*500)))
+		assert.Equal(t, throttleAllow, throttle.Check(2, "discipline|1.a.sig", now.Add(time.Millisecond*500)))
+		assert.Equal(t, throttleAllow, throttle.Check(1, "list|1.sig", now.Add(time.Millisecond*600)))
+		assert.Equal(t, throttleAllow, throttle.Check(1, "discipline|1.a.sig", now.Add(time.Millisecond*700)))
+		assert.Equal(t, throttleAllow, throttle.Check(1, "discipline|1.a.sig", now.Add(time.Millisecond*1700)))
+	})
+
+	t.Run("evict_idle", func(t *testing.T) {
+		throttle := NewSenderThrottle(rate.Inf, 1, time.Minute, time.Second, time.Second*10)
+		now := time.Now()
+
+		throttle.Check(1, "", now)
+		throttle.Check(2, "", now.Add(time.Second*30))
+		assert.Equal(t, 2, throttle.Len())
+
+		throttle.Check(3, "", now.Add(time.Minute+time.Second))
+		assert.Equal(t, 2, throttle.Len())
+	})
+}
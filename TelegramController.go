@@ -11,9 +11,12 @@ import (
 	"github.com/kneu-messenger-pigeon/events"
 	scoreApi "github.com/kneu-messenger-pigeon/score-api"
 	"github.com/kneu-messenger-pigeon/score-client"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/time/rate"
 	tele "gopkg.in/telebot.v3"
+	"hash/fnv"
 	"io"
+	"log/slog"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,52 +29,187 @@ const listCommand = "/list"
 
 const resetCommand = "/reset"
 
+const helpCommand = "/help"
+
 const TelegramControllerStartedMessage = "Telegram controller started\n"
 
 const sendRetryCount = 5
 
+const defaultDisciplinesPageSize = 8
+
+// Telegram does not allow to edit messages older than 48 hours
+const editableMessageMaxAge = time.Hour * 48
+
+// disciplines with names not longer than this are placed in two columns when it is enabled
+const shortDisciplineNameLength = 20
+
+const scoreServiceUnavailableText = "Сервіс оцінок тимчасово недоступний"
+
+// Messages sent as is, without the composer post filter, so they should not contain characters
+// reserved by MarkdownV2 or HTML parse mode.
+const (
+	scoreServiceUnavailableMessage = "⚠️ Оцінки тимчасово недоступні, спробуйте пізніше"
+	panicApologyText               = "😔 Вибачте, щось пішло не так, спробуйте пізніше"
+	throttledText                  = "⏳ Забагато запитів, зачекайте кілька секунд"
+	resetConfirmationText          = "Ви впевнені, що хочете вийти з облікового запису?"
+)
+
+const callbackErrorText = "Не вдалося виконати дію, спробуйте пізніше"
+
+const callbackExpiredText = "Кнопка застаріла, надішліть " + listCommand + " щоб оновити список"
+
+const resetCancelledText = "Вихід скасовано"
+
+// the confirmation of logout is deleted and its buttons are rejected after this time
+const resetConfirmationTTL = time.Minute * 5
+
+const authUrlButtonText = "🔑 Увійти через сайт КНЕУ"
+
+const alreadyAuthorizedText = "Ви вже увійшли, надішліть " + listCommand + " щоб переглянути оцінки"
+
 const SupportInfo = "Підтримка та ідеї: @KneuJournalSupportBot"
 
+var ErrBotUserNotResolved = errors.New("bot user is not resolved")
+
+var ErrPollingNotStarted = errors.New("polling is not started")
+
+type commandRoute struct {
+	command       string
+	description   string
+	descriptionEn string
+	handler       tele.HandlerFunc
+}
+
 type TelegramController struct {
 	out                            io.Writer
+	logger                         *slog.Logger
 	debugLogger                    *framework.DebugLogger
 	bot                            *tele.Bot
 	composer                       framework.MessageComposerInterface
+	helpComposer                   *HelpComposer
 	userRepository                 framework.UserRepositoryInterface
 	userLogoutHandler              framework.UserLogoutHandlerInterface
 	authorizerClient               authorizer.ClientInterface
 	scoreClient                    score.ClientInterface
+	scoreCircuitBreaker            *ScoreCircuitBreaker
 	welcomeAnonymousDelayedDeleter contracts.DeleterInterface
-
-	rateLimiter     *rate.Limiter
-	authRedirectUrl string
+	welcomeAnonymousExpiry         *MessageExpiry
+
+	rateLimiter           *rate.Limiter
+	chatRateLimiter       *ChatRateLimiter
+	retryPolicy           *RetryPolicy
+	handlerLimiter        *HandlerLimiter
+	senderThrottle        *SenderThrottle
+	callbackCodec         *CallbackCodec
+	scoreCache            *ScoreCache
+	outbox                *FileOutbox
+	adminServer           *AdminServer
+	authRedirectUrl       string
+	disciplinesPageSize   int
+	disciplinesTwoColumns bool
+	// ParseMode of bot settings, tele.ModeHTML or tele.ModeMarkdownV2; templates are converted by composer post filter
+	parseMode tele.ParseMode
+
+	// ctx is cancelled on shutdown to abort pending rate limiter waits and retries
+	ctx    context.Context
+	cancel context.CancelFunc
+	// poller wraps the bot poller in Execute, see Ready
+	poller *TrackedPoller
+
+	// outboxMutex serialises replayed and live notifications with the same outbox key
+	outboxMutex framework.MultiMutex
+	// replayedMessageIds keeps message ids sent by replay until the next live notification with the same outbox key,
+	// the framework could read the previous message id before replay saved it
+	replayedMessageIds sync.Map
+	// redis is shared by the app storages, the framework does not expose its client; closed by runApp
+	redis redis.UniversalClient
+	// scoreChangedMessageIdStorage saves message ids of replayed notifications for the framework
+	scoreChangedMessageIdStorage framework.ScoreChangedMessageIdStorageInterface
 
 	markups struct {
-		disciplineButton           *tele.InlineButton
-		listButton                 *tele.InlineButton
-		disciplineScoreReplyMarkup *tele.ReplyMarkup
-		authorizedUserReplyMarkup  *tele.ReplyMarkup
-		logoutUserReplyMarkup      *tele.ReplyMarkup
+		disciplineButton          *tele.InlineButton
+		listButton                *tele.InlineButton
+		retryListButton           *tele.InlineButton
+		previousPageButton        *tele.InlineButton
+		nextPageButton            *tele.InlineButton
+		resetConfirmButton        *tele.InlineButton
+		resetCancelButton         *tele.InlineButton
+		refreshAuthUrlButton      *tele.InlineButton
+		authorizedUserReplyMarkup *tele.ReplyMarkup
+		logoutUserReplyMarkup     *tele.ReplyMarkup
 	}
 }
 
-func NewTelegramController(serviceContainer *framework.ServiceContainer, bot *tele.Bot, out io.Writer) *TelegramController {
-	return &TelegramController{
+// NewTelegramController builds the controller with settings of the config, outbox file is opened when it is set.
+func NewTelegramController(
+	serviceContainer *framework.ServiceContainer, bot *tele.Bot, config Config, logger *slog.Logger, out io.Writer,
+) (*TelegramController, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scoreCircuitBreaker := NewDefaultScoreCircuitBreaker(NewMeasuredScoreClient(
+		NewTimeoutScoreClient(serviceContainer.ScoreClient, defaultScoreClientTimeout),
+	))
+
+	controller := &TelegramController{
 		out:                            out,
+		logger:                         logger,
 		debugLogger:                    serviceContainer.DebugLogger,
 		bot:                            bot,
 		composer:                       framework.NewMessageComposer(framework.MessageComposerConfig{}),
+		helpComposer:                   NewHelpComposer(),
 		userRepository:                 serviceContainer.UserRepository,
 		userLogoutHandler:              serviceContainer.UserLogoutHandler,
 		authorizerClient:               serviceContainer.AuthorizerClient,
-		scoreClient:                    serviceContainer.ScoreClient,
+		scoreClient:                    scoreCircuitBreaker,
+		scoreCircuitBreaker:            scoreCircuitBreaker,
 		welcomeAnonymousDelayedDeleter: serviceContainer.WelcomeAnonymousDelayedDeleter,
+		welcomeAnonymousExpiry:         NewMessageExpiry(),
 		rateLimiter:                    rate.NewLimiter(rate.Every(time.Second), 30),
+		chatRateLimiter:                NewDefaultChatRateLimiter(),
+		retryPolicy:                    NewDefaultRetryPolicy(),
+		callbackCodec:                  NewCallbackCodec(config.appSecret),
+		parseMode:                      config.parseMode,
+		disciplinesPageSize:            config.disciplinesPageSize,
+		disciplinesTwoColumns:          config.disciplinesTwoColumns,
+		handlerLimiter:                 NewHandlerLimiter(ctx, config.handlerConcurrency, config.handlerTimeout),
+		senderThrottle:                 NewDefaultSenderThrottle(),
+		ctx:                            ctx,
+		cancel:                         cancel,
 	}
+
+	if config.scoreCacheTTL > 0 {
+		controller.scoreCache = NewScoreCache(controller.scoreClient, config.scoreCacheTTL, scoreCacheMaxStaleAge)
+		controller.scoreClient = controller.scoreCache
+	}
+
+	if config.outboxFile != "" {
+		var err error
+		controller.outbox, err = OpenFileOutbox(config.outboxFile)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		controller.redis = redis.NewClient(config.redisOptions)
+		controller.scoreChangedMessageIdStorage = NewScoreChangedMessageIdStorage(
+			logger, controller.redis, config.repeatScoreChangesTimeframe,
+		)
+	}
+
+	if config.adminListen != "" {
+		controller.adminServer = NewAdminServer(config.adminListen, config.adminPprof, controller.Ready, logger)
+	}
+
+	return controller, nil
 }
 
 func (controller *TelegramController) Init() {
-	controller.composer.SetPostFilter(escapeMarkDown)
+	if controller.parseMode == tele.ModeHTML {
+		controller.composer.SetPostFilter(formatHtml)
+		controller.helpComposer.SetPostFilter(formatHtml)
+	} else {
+		controller.composer.SetPostFilter(formatMarkDown)
+		controller.helpComposer.SetPostFilter(formatMarkDown)
+	}
 	controller.authRedirectUrl = fmt.Sprintf("https://t.me/%s?start", controller.bot.Me.Username)
 
 	controller.markups.disciplineButton = &tele.InlineButton{
@@ -82,11 +220,34 @@ func (controller *TelegramController) Init() {
 		Unique: "list",
 	}
 
-	controller.markups.disciplineScoreReplyMarkup = &tele.ReplyMarkup{
-		OneTimeKeyboard: true,
-		InlineKeyboard: [][]tele.InlineButton{
-			{*controller.markups.listButton},
-		},
+	// shares the handler with list button to load disciplines again after score service failure
+	controller.markups.retryListButton = &tele.InlineButton{
+		Text:   "🔄 Спробувати ще раз",
+		Unique: "list",
+	}
+
+	// both buttons share the handler, page number is passed as data
+	controller.markups.previousPageButton = &tele.InlineButton{
+		Text:   "◀",
+		Unique: "page",
+	}
+	controller.markups.nextPageButton = &tele.InlineButton{
+		Text:   "▶",
+		Unique: "page",
+	}
+
+	controller.markups.resetConfirmButton = &tele.InlineButton{
+		Text:   "Так, вийти",
+		Unique: "resetConfirm",
+	}
+	controller.markups.resetCancelButton = &tele.InlineButton{
+		Text:   "Скасувати",
+		Unique: "resetCancel",
+	}
+
+	controller.markups.refreshAuthUrlButton = &tele.InlineButton{
+		Text:   "🔄 Отримати нове посилання",
+		Unique: "authUrl",
 	}
 
 	controller.markups.authorizedUserReplyMarkup = &tele.ReplyMarkup{
@@ -113,44 +274,275 @@ func (controller *TelegramController) Init() {
 func (controller *TelegramController) Execute(ctx context.Context, wg *sync.WaitGroup) {
 	controller.Init()
 
+	// wrapped before the admin server is started, so Ready reads the poller without a race
+	controller.poller = NewTrackedPoller(controller.bot.Poller)
+	controller.bot.Poller = controller.poller
+
+	if controller.outbox != nil {
+		wg.Add(1)
+		go controller.replayOutbox(wg)
+	}
+
+	controller.registerCommands()
+
+	if controller.adminServer != nil {
+		wg.Add(1)
+		go controller.adminServer.Execute(ctx, wg)
+	}
+
 	go controller.bot.Start()
 	_, _ = fmt.Fprint(controller.out, TelegramControllerStartedMessage)
 	<-ctx.Done()
+	controller.cancel()
 	controller.bot.Stop()
 	wg.Done()
 }
 
+// commandRoutes is the route table of commands, the bot menu is registered from it as well.
+func (controller *TelegramController) commandRoutes() []commandRoute {
+	return []commandRoute{
+		{
+			command:       startCommand,
+			description:   "Почати роботу з ботом",
+			descriptionEn: "Start the bot",
+			handler:       controller.DisciplinesListAction,
+		},
+		{
+			command:       listCommand,
+			description:   "Мої результати",
+			descriptionEn: "My scores",
+			handler:       controller.DisciplinesListAction,
+		},
+		{
+			command:       resetCommand,
+			description:   "Вийти з облікового запису",
+			descriptionEn: "Log out",
+			handler:       controller.ResetAction,
+		},
+		{
+			command:       helpCommand,
+			description:   "Довідка",
+			descriptionEn: "Help",
+			handler:       controller.HelpAction,
+		},
+	}
+}
+
+// registerCommands sets the menu of commands in private chats, English descriptions are shown
+// to users with "en" language of Telegram app.
+func (controller *TelegramController) registerCommands() {
+	// offline bot has no user, so there is no menu to register
+	if controller.bot.Me == nil || controller.bot.Me.ID == 0 {
+		return
+	}
+
+	scope := tele.CommandScope{Type: tele.CommandScopeAllPrivateChats}
+	for _, languageCode := range []string{"", "en"} {
+		routes := controller.commandRoutes()
+		commands := make([]tele.Command, len(routes))
+		for i, route := range routes {
+			commands[i] = tele.Command{Text: strings.TrimPrefix(route.command, "/"), Description: route.description}
+			if languageCode == "en" {
+				commands[i].Description = route.descriptionEn
+			}
+		}
+
+		err := controller.bot.SetCommands(commands, scope, languageCode)
+		if err != nil {
+			SetCommandsErrorCount.Inc()
+			controller.logger.With(errorLogAttrs(err)...).Error(
+				"Failed to set bot commands", slog.String("language_code", languageCode),
+			)
+		}
+	}
+}
+
+// Ready reports why the controller could not process updates yet: bot user is not resolved,
+// polling is not started or the circuit to score service is open.
+func (controller *TelegramController) Ready() error {
+	if controller.bot.Me == nil || controller.bot.Me.ID == 0 {
+		return ErrBotUserNotResolved
+	}
+
+	if controller.poller == nil || !controller.poller.Running() {
+		return ErrPollingNotStarted
+	}
+
+	if controller.scoreCircuitBreaker != nil && controller.scoreCircuitBreaker.State() == circuitOpen {
+		return ErrScoreCircuitOpen
+	}
+
+	return nil
+}
+
 func (controller *TelegramController) setupRoutes() {
+	controller.bot.Use(updateStartMiddleware)
+	// telebot does not recover panics, the outer recover catches panics of the middlewares before the limiter;
+	// the apology is not sent from there, since the panic could come from ThrottledAction sending to the sender
+	controller.bot.Use(recoverMiddleware(func(c tele.Context) error { return nil }))
+	controller.bot.Use(respondCallbackMiddleware(controller.debugLogger))
+	controller.bot.Use(throttleMiddleware(controller.senderThrottle, controller.ThrottledAction))
+	// handlers run in separate goroutine, so recoverMiddleware should follow the limiter
+	controller.bot.Use(controller.handlerLimiter.Middleware)
+	controller.bot.Use(recoverMiddleware(controller.PanicApologyAction))
 	controller.bot.Use(onlyPrivateChatMiddleware())
 	controller.bot.Use(authMiddleware(controller.userRepository))
-	controller.bot.Use(onlyAuthorizedMiddleware(controller.WelcomeAnonymousAction))
+	controller.bot.Use(onlyAuthorizedMiddleware(controller.WelcomeAnonymousAction, helpCommand))
 
-	controller.bot.Handle(resetCommand, controller.ResetAction)
-	controller.bot.Handle(startCommand, controller.DisciplinesListAction)
-	controller.bot.Handle(listCommand, controller.DisciplinesListAction)
+	for _, route := range controller.commandRoutes() {
+		controller.bot.Handle(route.command, route.handler)
+	}
 	controller.bot.Handle(controller.markups.listButton, controller.DisciplinesListAction)
 	controller.bot.Handle(controller.markups.disciplineButton, controller.DisciplineScoresAction)
+	controller.bot.Handle(controller.markups.nextPageButton, controller.DisciplinesPageAction)
+	controller.bot.Handle(controller.markups.resetConfirmButton, controller.ResetConfirmAction)
+	controller.bot.Handle(controller.markups.resetCancelButton, controller.ResetCancelAction)
+	controller.bot.Handle(controller.markups.refreshAuthUrlButton, controller.AlreadyAuthorizedAction)
 	controller.bot.Handle(tele.OnText, controller.DisciplinesListAction)
 }
 
-func (controller *TelegramController) ResetAction(c tele.Context) error {
+// PanicApologyAction tells the user that the request failed after the handler panic.
+func (controller *TelegramController) PanicApologyAction(c tele.Context) error {
+	if c.Chat() == nil && c.Sender() == nil {
+		return nil
+	}
+
+	// deadline of the update could be already exceeded
+	_, err := controller.send(controller.ctx, c.Recipient(), panicApologyText)
+	if err != nil {
+		controller.debugLogger.Log("PanicApologyAction: failed to send message: %v", err)
+	}
+
+	return err
+}
+
+// ThrottledAction tells the sender to slow down, callbacks are answered with a toast.
+func (controller *TelegramController) ThrottledAction(c tele.Context) error {
+	if c.Callback() != nil {
+		setCallbackResponse(c, throttledText, false)
+		return nil
+	}
+
+	_, err := controller.send(controller.ctx, c.Recipient(), throttledText)
+	return err
+}
+
+// ResetAction asks the student to confirm logout, the confirmation is deleted after resetConfirmationTTL.
+func (controller *TelegramController) ResetAction(c tele.Context) (err error) {
+	defer observeActionDuration("ResetAction", time.Now(), &err)
+
+	replyMarkup := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{
+				*controller.callbackButton(controller.markups.resetConfirmButton, CallbackPayload{}),
+				*controller.callbackButton(controller.markups.resetCancelButton, CallbackPayload{}),
+			},
+		},
+	}
+
+	message, err := controller.send(getUpdateContext(c, controller.ctx), c.Recipient(), resetConfirmationText, replyMarkup)
+	if err != nil {
+		return err
+	}
+
+	controller.welcomeAnonymousDelayedDeleter.AddToQueue(&contracts.DeleteTask{
+		ScheduledAt: time.Now().Add(resetConfirmationTTL).Unix(),
+		MessageId:   int32(message.ID),
+		ChatId:      c.Chat().ID,
+	})
+
+	return nil
+}
+
+// ResetConfirmAction logs the student out, confirmations older than resetConfirmationTTL are rejected as expired.
+func (controller *TelegramController) ResetConfirmAction(c tele.Context) (err error) {
+	defer observeActionDuration("ResetConfirmAction", time.Now(), &err)
+
+	if _, isValid := controller.decodeCallback(c); !isValid {
+		return nil
+	}
+
+	if time.Since(c.Message().Time()) > resetConfirmationTTL {
+		setCallbackResponse(c, callbackExpiredText, true)
+		return nil
+	}
+
+	controller.deleteResetConfirmation(c)
+
 	return controller.userLogoutHandler.Handle(strconv.FormatInt(c.Chat().ID, 10))
 }
 
-func (controller *TelegramController) WelcomeAnonymousAction(c tele.Context) error {
+func (controller *TelegramController) ResetCancelAction(c tele.Context) error {
+	if _, isValid := controller.decodeCallback(c); !isValid {
+		return nil
+	}
+
+	setCallbackResponse(c, resetCancelledText, false)
+	controller.deleteResetConfirmation(c)
+
+	return nil
+}
+
+// deleteResetConfirmation removes answered confirmation, the delayed deleter task will fail to delete it later, so
+// the failure is not reported.
+func (controller *TelegramController) deleteResetConfirmation(c tele.Context) {
+	err := controller.delete(getUpdateContext(c, controller.ctx), c.Message())
+	if err != nil {
+		controller.debugLogger.Log("deleteResetConfirmation: failed to delete message: %v", err)
+	}
+}
+
+// HelpAction explains how to log in for anonymous users and describes notifications and commands for students.
+func (controller *TelegramController) HelpAction(c tele.Context) (err error) {
+	defer observeActionDuration("HelpAction", time.Now(), &err)
+
+	messageData := HelpMessageData{
+		SupportInfo: markDownData(SupportInfo),
+	}
+
+	var message string
+	if student := getStudent(c); student != nil {
+		messageData.StudentMessageData = markDownStudentMessageData(student)
+		err, message = controller.helpComposer.ComposeHelpAuthorizedMessage(messageData)
+	} else {
+		err, message = controller.helpComposer.ComposeHelpAnonymousMessage(messageData)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = controller.send(getUpdateContext(c, controller.ctx), c.Recipient(), message)
+	return err
+}
+
+// WelcomeAnonymousAction sends the auth link as URL button, "get new link" button regenerates it in place.
+// Telegram LoginUrl button is not used, as the authorizer does not verify Telegram login data.
+func (controller *TelegramController) WelcomeAnonymousAction(c tele.Context) (err error) {
+	defer observeActionDuration("WelcomeAnonymousAction", time.Now(), &err)
+
+	// all updates of anonymous users are handled here, including the press of "get new link" button
+	refresh := c.Callback() != nil && c.Callback().Unique == controller.markups.refreshAuthUrlButton.Unique
+	if refresh {
+		if _, isValid := controller.decodeCallback(c); !isValid {
+			return nil
+		}
+	}
+
 	authUrl, expireAt, err := controller.authorizerClient.GetAuthUrl(
 		strconv.FormatInt(c.Chat().ID, 10),
 		controller.authRedirectUrl,
 	)
 
 	if err != nil {
-		_, _ = fmt.Fprintf(controller.out, "failed to get Auth url: %v\n", err)
+		controller.logger.With(errorLogAttrs(err)...).Error(
+			"Failed to get auth url", slog.String("action", "WelcomeAnonymousAction"), slog.Int64("chat_id", c.Chat().ID),
+		)
 		return err
 	}
 
 	err, messageText := controller.composer.ComposeWelcomeAnonymousMessage(
 		models.WelcomeAnonymousMessageData{
-			AuthUrl:  authUrl,
+			AuthUrl:  markDownData(authUrl),
 			ExpireAt: expireAt,
 		},
 	)
@@ -158,13 +550,28 @@ func (controller *TelegramController) WelcomeAnonymousAction(c tele.Context) err
 		return err
 	}
 
+	replyMarkup := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{{Text: authUrlButtonText, URL: authUrl}},
+			{*controller.callbackButton(controller.markups.refreshAuthUrlButton, CallbackPayload{})},
+		},
+	}
+
 	var message *tele.Message
-	message, err = controller.send(c.Recipient(), messageText, tele.Protected, controller.markups.logoutUserReplyMarkup)
+	if refresh {
+		message, err = controller.editOrSend(c, messageText, replyMarkup)
+	} else {
+		message, err = controller.send(getUpdateContext(c, controller.ctx), c.Recipient(), messageText, tele.Protected, replyMarkup)
+	}
 
 	if err != nil {
 		return err
 	}
 
+	// the message could be edited in place, so the deletion queued for the previous link should be skipped
+	if refresh {
+		controller.welcomeAnonymousExpiry.Prolong(c.Chat().ID, message.ID, expireAt.Unix())
+	}
 	controller.welcomeAnonymousDelayedDeleter.AddToQueue(&contracts.DeleteTask{
 		ScheduledAt: expireAt.Unix(),
 		MessageId:   int32(message.ID),
@@ -174,30 +581,53 @@ func (controller *TelegramController) WelcomeAnonymousAction(c tele.Context) err
 	return nil
 }
 
-func (controller *TelegramController) HandleDeleteTask(task *contracts.DeleteTask) error {
-	return controller.bot.Delete(tele.StoredMessage{
+// AlreadyAuthorizedAction answers "get new link" button pressed after the student is logged in.
+func (controller *TelegramController) AlreadyAuthorizedAction(c tele.Context) error {
+	setCallbackResponse(c, alreadyAuthorizedText, true)
+	return nil
+}
+
+func (controller *TelegramController) HandleDeleteTask(task *contracts.DeleteTask) (err error) {
+	defer observeActionDuration("HandleDeleteTask", time.Now(), &err)
+
+	if !controller.welcomeAnonymousExpiry.IsActual(task) {
+		return nil
+	}
+
+	return controller.delete(controller.ctx, tele.StoredMessage{
 		MessageID: strconv.Itoa(int(task.GetMessageId())),
 		ChatID:    task.GetChatId(),
 	})
 }
 
 func (controller *TelegramController) WelcomeAuthorizedAction(event *events.UserAuthorizedEvent) error {
+	return controller.WelcomeAuthorizedActionContext(controller.ctx, event)
+}
+
+func (controller *TelegramController) WelcomeAuthorizedActionContext(ctx context.Context, event *events.UserAuthorizedEvent) (err error) {
+	defer observeActionDuration("WelcomeAuthorizedAction", time.Now(), &err)
+
 	student := controller.userRepository.GetStudent(event.ClientUserId)
 
 	err, message := controller.composer.ComposeWelcomeAuthorizedMessage(
 		models.UserAuthorizedMessageData{
-			StudentMessageData: models.NewStudentMessageData(student),
+			StudentMessageData: markDownStudentMessageData(student),
 		},
 	)
 	if err == nil {
 		_, err = controller.send(
+			ctx,
 			makeChatId(event.ClientUserId),
 			message,
 			controller.markups.authorizedUserReplyMarkup,
 		)
 
 		if err != nil {
-			_, _ = fmt.Fprintf(controller.out, "WelcomeAuthorizedAction failed to send message: %v; text: %s\n", err, message)
+			controller.logger.With(errorLogAttrs(err)...).Error(
+				"Failed to send message", slog.String("action", "WelcomeAuthorizedAction"),
+				slog.String("chat_id", event.ClientUserId), slog.Uint64("student_id", uint64(event.StudentId)),
+				slog.String("text", message),
+			)
 		}
 	}
 
@@ -205,101 +635,377 @@ func (controller *TelegramController) WelcomeAuthorizedAction(event *events.User
 }
 
 func (controller *TelegramController) LogoutFinishedAction(event *events.UserAuthorizedEvent) error {
+	return controller.LogoutFinishedActionContext(controller.ctx, event)
+}
+
+func (controller *TelegramController) LogoutFinishedActionContext(ctx context.Context, event *events.UserAuthorizedEvent) (err error) {
+	defer observeActionDuration("LogoutFinishedAction", time.Now(), &err)
+
 	err, message := controller.composer.ComposeLogoutFinishedMessage()
 	if err == nil {
-		_, err = controller.send(makeChatId(event.ClientUserId), message, controller.markups.logoutUserReplyMarkup)
+		_, err = controller.send(ctx, makeChatId(event.ClientUserId), message, controller.markups.logoutUserReplyMarkup)
 
-		if err != nil && !isBlockedByUserErr(err) {
-			_, _ = fmt.Fprintf(controller.out, "LogoutFinishedAction failed to send message: %v; text: %s\n", err, message)
+		if err != nil && !isBlockedByUserErr(err) && !errors.Is(err, ErrSendCancelled) {
+			controller.logger.With(errorLogAttrs(err)...).Error(
+				"Failed to send message", slog.String("action", "LogoutFinishedAction"),
+				slog.String("chat_id", event.ClientUserId), slog.String("text", message),
+			)
 		}
 
 	}
 	return err
 }
 
-func (controller *TelegramController) DisciplinesListAction(c tele.Context) error {
+func (controller *TelegramController) DisciplinesListAction(c tele.Context) (err error) {
+	defer observeActionDuration("DisciplinesListAction", time.Now(), &err)
+
 	DisciplinesListActionRequestTotal.Inc()
 
 	student := getStudent(c)
 
-	disciplines, err := controller.scoreClient.GetStudentDisciplines(student.Id)
-	if err == nil {
-		replyMarkup := &tele.ReplyMarkup{
-			OneTimeKeyboard: true,
-			InlineKeyboard:  make([][]tele.InlineButton, len(disciplines)),
+	// typed commands open the first page, back button returns to the page of discipline
+	page := 0
+	if c.Callback() != nil {
+		payload, isValid := controller.decodeCallback(c)
+		if !isValid {
+			return nil
 		}
+		page = payload.Page
+	}
 
-		var disciplineButton *tele.InlineButton
-
-		for i, discipline := range disciplines {
-			disciplineButton = controller.markups.disciplineButton.With(strconv.Itoa(discipline.Discipline.Id))
-			disciplineButton.Text = discipline.Discipline.Name
-
-			replyMarkup.InlineKeyboard[i] = []tele.InlineButton{
-				*disciplineButton,
-			}
-		}
+	disciplines, err := controller.scoreClient.GetStudentDisciplines(student.Id)
+	if err == nil {
+		replyMarkup := controller.makeDisciplinesReplyMarkup(disciplines, page)
 
 		var message string
 		err, message = controller.composer.ComposeDisciplinesListMessage(
 			models.DisciplinesListMessageData{
-				StudentMessageData: models.NewStudentMessageData(student),
-				Disciplines:        disciplines,
-				SupportInfo:        SupportInfo,
+				StudentMessageData: markDownStudentMessageData(student),
+				Disciplines:        markDownDisciplineScoreResults(disciplines),
+				SupportInfo:        markDownData(SupportInfo),
 			},
 		)
 		if err == nil {
-			_, err = controller.send(c.Recipient(), message, replyMarkup)
+			_, err = controller.editOrSend(c, message, replyMarkup)
 		}
+	} else {
+		setCallbackResponse(c, scoreServiceUnavailableText, false)
+		controller.sendScoreServiceUnavailable(c, page)
 	}
 
 	return err
 }
 
-func (controller *TelegramController) DisciplineScoresAction(c tele.Context) error {
+// sendScoreServiceUnavailable replaces disciplines list with a notice and a retry button keeping the page.
+func (controller *TelegramController) sendScoreServiceUnavailable(c tele.Context, page int) {
+	replyMarkup := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{*controller.callbackButton(controller.markups.retryListButton, CallbackPayload{Page: page})},
+		},
+	}
+
+	_, err := controller.editOrSend(c, scoreServiceUnavailableMessage, replyMarkup)
+	if err != nil {
+		controller.debugLogger.Log("sendScoreServiceUnavailable: failed to send message: %v", err)
+	}
+}
+
+// DisciplinesPageAction switches page of disciplines keyboard, the page is taken from callback data.
+func (controller *TelegramController) DisciplinesPageAction(c tele.Context) (err error) {
+	defer observeActionDuration("DisciplinesPageAction", time.Now(), &err)
+
+	DisciplinesPageActionRequestTotal.Inc()
+
+	student := getStudent(c)
+	payload, isValid := controller.decodeCallback(c)
+	if !isValid {
+		return nil
+	}
+
+	disciplines, err := controller.scoreClient.GetStudentDisciplines(student.Id)
+	if err == nil && c.Message() != nil {
+		replyMarkup := controller.makeDisciplinesReplyMarkup(disciplines, payload.Page)
+		_, err = controller.callWithRetry(
+			getUpdateContext(c, controller.ctx), "editMessageReplyMarkup", c.Chat().ID,
+			func() (*tele.Message, error) {
+				return controller.bot.EditReplyMarkup(c.Message(), replyMarkup)
+			},
+		)
+	} else if err != nil {
+		setCallbackResponse(c, scoreServiceUnavailableText, false)
+	}
+
+	return err
+}
+
+// makeDisciplinesReplyMarkup builds inline keyboard with disciplines of the page and navigation buttons.
+// Short names are placed in two columns when enabled; page size 0 disables pagination.
+func (controller *TelegramController) makeDisciplinesReplyMarkup(disciplines scoreApi.DisciplineScoreResults, page int) *tele.ReplyMarkup {
+	pageDisciplines := disciplines
+	pagesCount := 1
+	if controller.disciplinesPageSize > 0 && len(disciplines) > controller.disciplinesPageSize {
+		pagesCount = (len(disciplines) + controller.disciplinesPageSize - 1) / controller.disciplinesPageSize
+		page = max(0, min(page, pagesCount-1))
+
+		offset := page * controller.disciplinesPageSize
+		pageDisciplines = disciplines[offset:min(offset+controller.disciplinesPageSize, len(disciplines))]
+	}
+
+	replyMarkup := &tele.ReplyMarkup{
+		OneTimeKeyboard: true,
+		InlineKeyboard:  make([][]tele.InlineButton, 0, len(pageDisciplines)+1),
+	}
+
+	var disciplineButton *tele.InlineButton
+	for _, discipline := range pageDisciplines {
+		disciplineButton = controller.callbackButton(controller.markups.disciplineButton, CallbackPayload{
+			DisciplineId: discipline.Discipline.Id,
+			Page:         page,
+		})
+		disciplineButton.Text = discipline.Discipline.Name
+
+		lastRow := len(replyMarkup.InlineKeyboard) - 1
+		if controller.disciplinesTwoColumns && lastRow >= 0 &&
+			len(replyMarkup.InlineKeyboard[lastRow]) == 1 &&
+			isShortDisciplineName(replyMarkup.InlineKeyboard[lastRow][0].Text) &&
+			isShortDisciplineName(disciplineButton.Text) {
+			replyMarkup.InlineKeyboard[lastRow] = append(replyMarkup.InlineKeyboard[lastRow], *disciplineButton)
+		} else {
+			replyMarkup.InlineKeyboard = append(replyMarkup.InlineKeyboard, []tele.InlineButton{*disciplineButton})
+		}
+	}
+
+	if pagesCount > 1 {
+		var navigationRow []tele.InlineButton
+		if page > 0 {
+			navigationRow = append(navigationRow, *controller.callbackButton(
+				controller.markups.previousPageButton, CallbackPayload{Page: page - 1},
+			))
+		}
+		if page < pagesCount-1 {
+			navigationRow = append(navigationRow, *controller.callbackButton(
+				controller.markups.nextPageButton, CallbackPayload{Page: page + 1},
+			))
+		}
+		replyMarkup.InlineKeyboard = append(replyMarkup.InlineKeyboard, navigationRow)
+	}
+
+	return replyMarkup
+}
+
+func (controller *TelegramController) DisciplineScoresAction(c tele.Context) (err error) {
+	defer observeActionDuration("DisciplineScoresAction", time.Now(), &err)
+
 	DisciplineScoresActionRequestTotal.Inc()
 
-	var message string
 	student := getStudent(c)
-	disciplineId, _ := strconv.Atoi(c.Callback().Data)
+	payload, isValid := controller.decodeCallback(c)
+	if !isValid {
+		return nil
+	}
 
-	discipline, err := controller.scoreClient.GetStudentDiscipline(student.Id, disciplineId)
+	discipline, err := controller.scoreClient.GetStudentDiscipline(student.Id, payload.DisciplineId)
 
 	if err != nil {
-		controller.removeReplyMarkup(c.Message())
+		setCallbackResponse(c, scoreServiceUnavailableText, true)
+		// the keyboard is removed, so the student should notice the failure; the notification keeps its button
+		if payload.ViewMode != viewModeNotification {
+			controller.removeReplyMarkup(getUpdateContext(c, controller.ctx), c.Message())
+		}
 	} else {
+		var message string
 		err, message = controller.composer.ComposeDisciplineScoresMessage(
 			models.DisciplinesScoresMessageData{
-				StudentMessageData: models.NewStudentMessageData(student),
-				Discipline:         discipline,
+				StudentMessageData: markDownStudentMessageData(student),
+				Discipline:         markDownDisciplineScoreResult(discipline),
 			},
 		)
 
 		if err == nil {
-			_, err = controller.send(c.Recipient(), message, controller.markups.disciplineScoreReplyMarkup)
+			replyMarkup := &tele.ReplyMarkup{
+				OneTimeKeyboard: true,
+				InlineKeyboard: [][]tele.InlineButton{
+					{*controller.callbackButton(controller.markups.listButton, CallbackPayload{Page: payload.Page})},
+				},
+			}
+			// the notification is edited by the framework on the next change of the score, so it is not replaced
+			if payload.ViewMode == viewModeNotification {
+				_, err = controller.send(getUpdateContext(c, controller.ctx), c.Recipient(), message, replyMarkup)
+			} else {
+				_, err = controller.editOrSend(c, message, replyMarkup)
+			}
 		}
 	}
 
-	if err != nil && strings.Contains(err.Error(), "Bad Request: can't parse entities") {
-		_, _ = fmt.Fprintf(controller.out, "DisciplineScoresAction failed to send message: %v; text: %s\n", err, message)
+	return err
+}
+
+// callbackButton returns copy of the button with signed payload.
+func (controller *TelegramController) callbackButton(button *tele.InlineButton, payload CallbackPayload) *tele.InlineButton {
+	return button.With(controller.callbackCodec.Encode(button.Unique, payload))
+}
+
+// decodeCallback reads payload of pressed button, forged and outdated buttons get "button expired" answer.
+func (controller *TelegramController) decodeCallback(c tele.Context) (CallbackPayload, bool) {
+	payload, err := controller.callbackCodec.Decode(c.Callback().Unique, c.Callback().Data)
+	if err != nil {
+		CallbackPayloadErrorCount.Inc()
+		controller.debugLogger.Log("decodeCallback: reject %s|%s: %v", c.Callback().Unique, c.Callback().Data, err)
+		setCallbackResponse(c, callbackExpiredText, true)
 	}
 
-	return err
+	return payload, err == nil
 }
 
 func (controller *TelegramController) ScoreChangedAction(
 	chatId string, previousMessageId string,
 	disciplineScore *scoreApi.DisciplineScore, previousScore *scoreApi.Score,
+) (err error, messageId string) {
+	return controller.ScoreChangedActionContext(controller.ctx, chatId, previousMessageId, disciplineScore, previousScore)
+}
+
+func (controller *TelegramController) ScoreChangedActionContext(
+	ctx context.Context, chatId string, previousMessageId string,
+	disciplineScore *scoreApi.DisciplineScore, previousScore *scoreApi.Score,
+) (err error, messageId string) {
+	defer observeActionDuration("ScoreChangedAction", time.Now(), &err)
+
+	var student *models.Student
+	if controller.scoreCache != nil || controller.outbox != nil {
+		student = controller.userRepository.GetStudent(chatId)
+	}
+
+	if controller.scoreCache != nil && student != nil {
+		controller.scoreCache.Invalidate(student.Id, disciplineScore.Discipline.Id)
+	}
+
+	if controller.outbox == nil {
+		return controller.scoreChangedAction(ctx, chatId, previousMessageId, disciplineScore, previousScore)
+	}
+
+	entry := &OutboxEntry{
+		ChatId:            chatId,
+		PreviousMessageId: previousMessageId,
+		DisciplineScore:   *disciplineScore,
+		PreviousScore:     *previousScore,
+		CreatedAt:         time.Now(),
+	}
+	if student != nil {
+		entry.StudentId = student.Id
+	}
+
+	mutex := controller.outboxMutex.Get(outboxMutexKey(entry))
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if replayedMessageId, exists := controller.replayedMessageIds.LoadAndDelete(entry.Key()); exists {
+		entry.PreviousMessageId = replayedMessageId.(string)
+	}
+
+	outboxErr := controller.outbox.Put(entry)
+	if outboxErr != nil {
+		OutboxErrorCount.Inc()
+		controller.logger.With(errorLogAttrs(outboxErr)...).Error(
+			"Failed to write outbox entry", slog.String("action", "ScoreChangedAction"),
+			slog.String("chat_id", chatId), slog.String("outbox_key", entry.Key()),
+		)
+	}
+
+	err, messageId = controller.scoreChangedAction(
+		ctx, chatId, entry.PreviousMessageId, disciplineScore, previousScore,
+	)
+	controller.ackOutboxEntry(entry, err)
+
+	return err, messageId
+}
+
+// replayOutbox repeats notifications interrupted by the previous shutdown and saves their message ids
+// like the framework does, so the next change of the score edits the replayed message.
+func (controller *TelegramController) replayOutbox(wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for _, entry := range controller.outbox.Pending() {
+		if controller.ctx.Err() != nil {
+			return
+		}
+
+		controller.replayOutboxEntry(entry)
+	}
+}
+
+func (controller *TelegramController) replayOutboxEntry(entry *OutboxEntry) {
+	mutex := controller.outboxMutex.Get(outboxMutexKey(entry))
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	// the live notification with the same key is already sent
+	if !controller.outbox.IsPending(entry) {
+		return
+	}
+
+	OutboxReplayedTotal.Inc()
+	err, messageId := controller.scoreChangedAction(
+		controller.ctx, entry.ChatId, entry.PreviousMessageId, &entry.DisciplineScore, &entry.PreviousScore,
+	)
+	controller.debugLogger.Log(
+		"replayOutbox: replay %s; err: %v; message id: %s", entry.Key(), err, messageId,
+	)
+
+	if isUndeliveredSendErr(err) {
+		return
+	}
+
+	// the same condition as framework.ScoreChangedEventHandler uses to save the new message id
+	if messageId != entry.PreviousMessageId && (messageId != "" || err == nil) {
+		controller.replayedMessageIds.Store(entry.Key(), messageId)
+		if controller.scoreChangedMessageIdStorage != nil && entry.StudentId != 0 {
+			controller.scoreChangedMessageIdStorage.Set(
+				uint(entry.StudentId), uint(entry.DisciplineScore.Score.Lesson.Id), entry.ChatId, messageId,
+			)
+		}
+	}
+
+	controller.ackOutboxEntry(entry, err)
+}
+
+// ackOutboxEntry keeps the entry for replay only if the message still could be delivered
+func (controller *TelegramController) ackOutboxEntry(entry *OutboxEntry, err error) {
+	if isUndeliveredSendErr(err) {
+		return
+	}
+
+	outboxErr := controller.outbox.Ack(entry)
+	if outboxErr != nil {
+		OutboxErrorCount.Inc()
+		controller.logger.With(errorLogAttrs(outboxErr)...).Error(
+			"Failed to ack outbox entry", slog.String("action", "ScoreChangedAction"),
+			slog.String("chat_id", entry.ChatId), slog.String("outbox_key", entry.Key()),
+		)
+	}
+}
+
+func outboxMutexKey(entry *OutboxEntry) uint {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(entry.Key()))
+	return uint(hash.Sum32())
+}
+
+func (controller *TelegramController) scoreChangedAction(
+	ctx context.Context, chatId string, previousMessageId string,
+	disciplineScore *scoreApi.DisciplineScore, previousScore *scoreApi.Score,
 ) (err error, messageId string) {
 	messageData := models.ScoreChangedMessageData{
-		Discipline: disciplineScore.Discipline,
-		Score:      disciplineScore.Score,
-		Previous:   *previousScore,
+		Discipline: markDownDiscipline(disciplineScore.Discipline),
+		Score:      markDownScore(disciplineScore.Score),
+		Previous:   markDownScore(*previousScore),
 	}
 
 	err, messageText := controller.composer.ComposeScoreChanged(messageData)
 	if err == nil {
-		disciplineButton := controller.markups.disciplineButton.With(strconv.Itoa(disciplineScore.Discipline.Id))
+		disciplineButton := controller.callbackButton(controller.markups.disciplineButton, CallbackPayload{
+			DisciplineId: disciplineScore.Discipline.Id,
+			ViewMode:     viewModeNotification,
+		})
 		disciplineButton.Text = disciplineScore.Discipline.Name
 
 		replyMarkup := &tele.ReplyMarkup{
@@ -315,7 +1021,7 @@ func (controller *TelegramController) ScoreChangedAction(
 		var message *tele.Message
 		if disciplineScore.Score.IsEqual(previousScore) {
 			if previousMessageId != "" {
-				err = controller.bot.Delete(tele.StoredMessage{
+				err = controller.delete(ctx, tele.StoredMessage{
 					MessageID: previousMessageId,
 					ChatID:    chatIdInt64,
 				})
@@ -326,14 +1032,14 @@ func (controller *TelegramController) ScoreChangedAction(
 			}
 
 		} else if previousMessageId == "" {
-			message, err = controller.send(tele.ChatID(chatIdInt64), messageText, replyMarkup)
+			message, err = controller.send(ctx, tele.ChatID(chatIdInt64), messageText, replyMarkup)
 			controller.debugLogger.Log(
 				"ScoreChangedAction: send new message to %s; err: %v; message: %#v",
 				chatId, err, message,
 			)
 
 		} else {
-			message, err = controller.bot.Edit(tele.StoredMessage{
+			message, err = controller.edit(ctx, tele.StoredMessage{
 				MessageID: previousMessageId,
 				ChatID:    chatIdInt64,
 			}, messageText, replyMarkup)
@@ -344,7 +1050,10 @@ func (controller *TelegramController) ScoreChangedAction(
 			)
 
 			if errors.Is(err, tele.ErrSameMessageContent) || errors.Is(err, tele.ErrMessageNotModified) {
-				_, _ = fmt.Fprintln(controller.out, `Ignore error "message not modified"`)
+				controller.logger.Info(
+					`Ignore error "message not modified"`, slog.String("action", "ScoreChangedAction"),
+					slog.String("chat_id", chatId), slog.String("message_id", previousMessageId),
+				)
 				return nil, previousMessageId
 			}
 		}
@@ -359,26 +1068,178 @@ func (controller *TelegramController) ScoreChangedAction(
 	return err, ""
 }
 
-func (controller *TelegramController) send(to tele.Recipient, what interface{}, opts ...interface{}) (message *tele.Message, err error) {
+// send delivers the message, text longer than Telegram limit is sent as several messages in order,
+// reply markup is attached to the last one.
+func (controller *TelegramController) send(ctx context.Context, to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error) {
+	text, isText := what.(string)
+	if !isText || len(text) <= telegramMessageLengthLimit {
+		return controller.sendPart(ctx, to, what, opts...)
+	}
+
+	parts := splitMessage(text, telegramMessageLengthLimit, controller.parseMode)
+	partOpts := withoutReplyMarkup(opts)
+
+	var message *tele.Message
+	var err error
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			partOpts = opts
+		}
+
+		message, err = controller.sendPart(ctx, to, part, partOpts...)
+		if err != nil {
+			break
+		}
+	}
+
+	return message, err
+}
+
+func (controller *TelegramController) sendPart(ctx context.Context, to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error) {
+	chatId, _ := strconv.ParseInt(to.Recipient(), 10, 64)
+
+	return controller.withPlainTextFallback(what, opts, func(what interface{}, opts []interface{}) (*tele.Message, error) {
+		return controller.callWithRetry(ctx, "sendMessage", chatId, func() (*tele.Message, error) {
+			return controller.bot.Send(to, what, opts...)
+		})
+	})
+}
+
+func (controller *TelegramController) edit(ctx context.Context, message tele.Editable, what interface{}, opts ...interface{}) (*tele.Message, error) {
+	_, chatId := message.MessageSig()
+
+	return controller.withPlainTextFallback(what, opts, func(what interface{}, opts []interface{}) (*tele.Message, error) {
+		return controller.callWithRetry(ctx, "editMessageText", chatId, func() (*tele.Message, error) {
+			return controller.bot.Edit(message, what, opts...)
+		})
+	})
+}
+
+// withPlainTextFallback repeats the call with markup stripped and parse mode disabled,
+// when Telegram fails to parse entities of the text, so the student receives the message anyway.
+func (controller *TelegramController) withPlainTextFallback(
+	what interface{}, opts []interface{}, call func(what interface{}, opts []interface{}) (*tele.Message, error),
+) (*tele.Message, error) {
+	message, err := call(what, opts)
+
+	text, isText := what.(string)
+	if isText && isParseEntitiesErr(err) {
+		ParseEntitiesErrorCount.Inc()
+		controller.logger.With(errorLogAttrs(err)...).Warn(
+			"Failed to parse entities, resend as plain text", slog.String("text", text),
+		)
+
+		plainTextOpts := append(opts[:len(opts):len(opts)], tele.ModeDefault)
+		message, err = call(controller.stripFormatting(text), plainTextOpts)
+	}
+
+	return message, err
+}
+
+// editOrSend replaces the message with pressed inline button, so navigation does not fill the chat with stale screens.
+// Typed commands, texts over the length limit and messages which could not be edited anymore get a new message.
+func (controller *TelegramController) editOrSend(c tele.Context, text string, replyMarkup *tele.ReplyMarkup) (*tele.Message, error) {
+	ctx := getUpdateContext(c, controller.ctx)
+	callback := c.Callback()
+	if callback == nil || callback.Message == nil ||
+		time.Since(callback.Message.Time()) > editableMessageMaxAge ||
+		utf16Length([]rune(text)) > telegramMessageLengthLimit {
+		return controller.send(ctx, c.Recipient(), text, replyMarkup)
+	}
+
+	message, err := controller.edit(ctx, callback.Message, text, replyMarkup)
+	if errors.Is(err, tele.ErrMessageNotModified) || errors.Is(err, tele.ErrSameMessageContent) {
+		return callback.Message, nil
+	}
+
+	if isMessageNotEditableErr(err) {
+		controller.debugLogger.Log("editOrSend: send new message instead of %d: %v", callback.Message.ID, err)
+		return controller.send(ctx, c.Recipient(), text, replyMarkup)
+	}
+
+	return message, err
+}
+
+func (controller *TelegramController) stripFormatting(text string) string {
+	if controller.parseMode == tele.ModeHTML {
+		return stripHtml(text)
+	}
+
+	return stripMarkDown(text)
+}
+
+func (controller *TelegramController) delete(ctx context.Context, message tele.Editable) error {
+	_, chatId := message.MessageSig()
+
+	_, err := controller.callWithRetry(ctx, "deleteMessage", chatId, func() (*tele.Message, error) {
+		return nil, controller.bot.Delete(message)
+	})
+	return err
+}
+
+// callWithRetry waits for rate limiters and repeats the Bot API call on flood and transient errors.
+// chatId 0 means that recipient is not a numeric chat and per-chat limit is not applied.
+// method is the name of Bot API method for latency metrics.
+func (controller *TelegramController) callWithRetry(
+	ctx context.Context, method string, chatId int64, call func() (*tele.Message, error),
+) (message *tele.Message, err error) {
 	floodError := &tele.FloodError{}
 
-	for i := 0; i < sendRetryCount; i++ {
-		err = controller.rateLimiter.Wait(context.Background())
+	for attempt := 0; attempt < controller.retryPolicy.maxAttempts; attempt++ {
+		err = nil
+		if chatId != 0 {
+			err = controller.chatRateLimiter.Wait(ctx, chatId)
+		}
+		if err == nil {
+			err = controller.rateLimiter.Wait(ctx)
+		}
+		if ctx.Err() != nil {
+			TelegramCallCancelledTotal.Inc()
+			return nil, newSendCancelledError(ctx)
+		}
 		if err != nil {
 			RateLimitErrorCount.Inc()
 			return nil, err
 		}
 
-		message, err = controller.bot.Send(to, what, opts...)
-		if errors.As(err, floodError) {
+		start := time.Now()
+		message, err = call()
+		errorClass := classifySendError(err)
+		TelegramApiDurationHistogram(method, errorClass).UpdateDuration(start)
+
+		var delay time.Duration
+		switch errorClass {
+		case sendErrorNone:
+			TelegramCallSuccessTotal.Inc()
+			return message, nil
+
+		case sendErrorFlood:
+			errors.As(err, floodError)
 			TooManyRequestsCount.Inc()
-			time.Sleep(time.Second * time.Duration(floodError.RetryAfter))
-			continue
+			TelegramRetryFloodTotal.Inc()
+			delay = time.Second * time.Duration(floodError.RetryAfter)
+
+		case sendErrorTransient:
+			TelegramRetryTransientTotal.Inc()
+			delay = controller.retryPolicy.Backoff(attempt)
+
+		default:
+			TelegramCallPermanentErrorTotal.Inc()
+			return message, err
+		}
+
+		if attempt == controller.retryPolicy.maxAttempts-1 {
+			break
 		}
 
-		return message, err
+		sleepErr := sleepContext(ctx, delay)
+		if sleepErr != nil {
+			TelegramCallCancelledTotal.Inc()
+			return nil, sleepErr
+		}
 	}
 
+	TelegramCallRetryExhaustedTotal.Inc()
 	return nil, err
 }
 
@@ -391,11 +1252,16 @@ func (controller *TelegramController) handleTelegramError(err error, chatId int6
 	return err
 }
 
-func (controller *TelegramController) removeReplyMarkup(message tele.Editable) {
+func (controller *TelegramController) removeReplyMarkup(ctx context.Context, message tele.Editable) {
 	if message != nil {
-		_, err := controller.bot.EditReplyMarkup(message, nil)
+		_, chatId := message.MessageSig()
+		_, err := controller.callWithRetry(ctx, "editMessageReplyMarkup", chatId, func() (*tele.Message, error) {
+			return controller.bot.EditReplyMarkup(message, nil)
+		})
 		if err != nil {
-			_, _ = fmt.Fprintf(controller.out, "Failed to remove reply markup: %v\n", err)
+			controller.logger.With(errorLogAttrs(err)...).Error(
+				"Failed to remove reply markup", slog.Int64("chat_id", chatId),
+			)
 		}
 	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,16 +14,20 @@ import (
 	"github.com/kneu-messenger-pigeon/client-framework/models"
 	"github.com/kneu-messenger-pigeon/events"
 	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/kneu-messenger-pigeon/score-client"
 	scoreMocks "github.com/kneu-messenger-pigeon/score-client/mocks"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/time/rate"
 	tele "gopkg.in/telebot.v3"
 	"io"
 	"net/http"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -41,10 +46,13 @@ var testPref = tele.Settings{
 	Poller: &tele.LongPoller{
 		Timeout: time.Minute,
 	},
-	ParseMode:   tele.ModeMarkdown,
+	ParseMode:   tele.ModeMarkdownV2,
 	Synchronous: true,
 }
 
+// testParseModes are used by tests of the output which depends on parse mode
+var testParseModes = []tele.ParseMode{tele.ModeMarkdownV2, tele.ModeHTML}
+
 func getTestSampleMessage() tele.Message {
 	return tele.Message{
 		ID:   testTelegramIncomingMessageId,
@@ -82,11 +90,76 @@ func NewGock() *gock.Request {
 	return gock.New(testTelegramURL + "/" + "bot" + testTelegramToken)
 }
 
-func CreateTelegramController(t *testing.T) (telegramController *TelegramController) {
+func TestNewTelegramController(t *testing.T) {
+	serviceContainer := &framework.ServiceContainer{
+		DebugLogger: &framework.DebugLogger{},
+		ScoreClient: &score.Client{},
+	}
+	logger := newLogger(&bytes.Buffer{}, logFormatJson)
+
+	t.Run("config", func(t *testing.T) {
+		config := Config{
+			appSecret:             "test-secret",
+			parseMode:             tele.ModeHTML,
+			disciplinesPageSize:   5,
+			disciplinesTwoColumns: true,
+			scoreCacheTTL:         time.Minute,
+			handlerTimeout:        defaultHandlerTimeout,
+			handlerConcurrency:    defaultHandlerConcurrency,
+			outboxFile:            filepath.Join(t.TempDir(), "outbox.log"),
+			redisOptions:          &redis.Options{},
+			adminListen:           ":0",
+		}
+
+		telegramController, err := NewTelegramController(serviceContainer, nil, config, logger, &bytes.Buffer{})
+		assert.NoError(t, err)
+		defer telegramController.outbox.Close()
+
+		assert.Same(t, logger, telegramController.logger)
+		assert.Equal(t, tele.ModeHTML, telegramController.parseMode)
+		assert.Equal(t, 5, telegramController.disciplinesPageSize)
+		assert.True(t, telegramController.disciplinesTwoColumns)
+		assert.Equal(t, NewCallbackCodec("test-secret"), telegramController.callbackCodec)
+		assert.Same(t, telegramController.scoreCache, telegramController.scoreClient)
+		assert.NotNil(t, telegramController.handlerLimiter)
+		assert.NotNil(t, telegramController.outbox)
+		assert.NotNil(t, telegramController.scoreChangedMessageIdStorage)
+		assert.NotNil(t, telegramController.redis)
+		assert.NotNil(t, telegramController.adminServer)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		telegramController, err := NewTelegramController(
+			serviceContainer, nil, Config{parseMode: tele.ModeMarkdownV2}, logger, &bytes.Buffer{},
+		)
+		assert.NoError(t, err)
+
+		assert.Nil(t, telegramController.scoreCache)
+		assert.Same(t, telegramController.scoreCircuitBreaker, telegramController.scoreClient)
+		assert.Nil(t, telegramController.outbox)
+		assert.Nil(t, telegramController.adminServer)
+	})
+
+	t.Run("outbox_error", func(t *testing.T) {
+		config := Config{outboxFile: filepath.Join(t.TempDir(), "not-exists", "outbox.log")}
+
+		telegramController, err := NewTelegramController(serviceContainer, nil, config, logger, &bytes.Buffer{})
+		assert.Error(t, err)
+		assert.Nil(t, telegramController)
+	})
+}
+
+func CreateTelegramController(t *testing.T) *TelegramController {
+	return CreateTelegramControllerWithParseMode(t, testPref.ParseMode)
+}
+
+func CreateTelegramControllerWithParseMode(t *testing.T, parseMode tele.ParseMode) (telegramController *TelegramController) {
 	testPref.OnError = func(err error, c tele.Context) {
 		lastTelegramErr = err
 	}
-	bot, _ := tele.NewBot(testPref)
+	pref := testPref
+	pref.ParseMode = parseMode
+	bot, _ := tele.NewBot(pref)
 
 	defer gock.Off()
 	NewGock().Times(0)
@@ -94,18 +167,33 @@ func CreateTelegramController(t *testing.T) (telegramController *TelegramControl
 	messageCompose := mocks.NewMessageComposerInterface(t)
 	messageCompose.On("SetPostFilter", mock.AnythingOfType("func(string) string")).Once().Return()
 
+	out := &bytes.Buffer{}
 	telegramController = &TelegramController{
-		out:                            &bytes.Buffer{},
+		out:                            out,
+		logger:                         newLogger(out, logFormatText),
 		debugLogger:                    &framework.DebugLogger{},
 		bot:                            bot,
 		composer:                       messageCompose,
+		helpComposer:                   NewHelpComposer(),
 		userRepository:                 mocks.NewUserRepositoryInterface(t),
 		userLogoutHandler:              mocks.NewUserLogoutHandlerInterface(t),
 		authorizerClient:               authorizerMocks.NewClientInterface(t),
 		scoreClient:                    scoreMocks.NewClientInterface(t),
 		welcomeAnonymousDelayedDeleter: mocks.NewDeleterInterface(t),
+		welcomeAnonymousExpiry:         NewMessageExpiry(),
 		rateLimiter:                    rate.NewLimiter(rate.Every(time.Second), 30),
+		chatRateLimiter:                NewDefaultChatRateLimiter(),
+		retryPolicy: &RetryPolicy{
+			maxAttempts: sendRetryCount,
+			baseDelay:   time.Millisecond * 10,
+			maxDelay:    time.Millisecond * 50,
+		},
+		callbackCodec: NewCallbackCodec("test-secret"),
+		parseMode:     parseMode,
 	}
+	telegramController.ctx, telegramController.cancel = context.WithCancel(context.Background())
+	telegramController.handlerLimiter = NewHandlerLimiter(telegramController.ctx, defaultHandlerConcurrency, defaultHandlerTimeout)
+	telegramController.senderThrottle = NewDefaultSenderThrottle()
 	telegramController.Init()
 
 	assert.True(t, gock.IsDone())
@@ -158,22 +246,70 @@ func TestTelegramController_Init(t *testing.T) {
 	assert.NotEmpty(t, markups.authorizedUserReplyMarkup)
 	assert.True(t, strings.HasPrefix(markups.authorizedUserReplyMarkup.ReplyKeyboard[0][0].Text, listCommand))
 
-	assert.NotEmpty(t, markups.disciplineScoreReplyMarkup)
+	assert.NotEmpty(t, markups.previousPageButton)
+	assert.NotEmpty(t, markups.nextPageButton)
 	assert.True(t, strings.HasPrefix(markups.authorizedUserReplyMarkup.ReplyKeyboard[0][0].Text, listCommand))
 }
 
 func TestTelegramController_ResetAction(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
-		telegramController := CreateTelegramController(t)
+	testCallbackId := "callback-reset"
+	answerCallbackSuccessResponse := map[string]interface{}{"ok": true, "result": true}
 
-		userLogoutHandler := telegramController.userLogoutHandler.(*mocks.UserLogoutHandlerInterface)
-		userLogoutHandler.On("Handle", testTelegramUserIdString).Return(nil).Once()
+	processResetButton := func(telegramController *TelegramController, button *tele.InlineButton, sentAt time.Time) {
+		button = telegramController.callbackButton(button, CallbackPayload{})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
+		message.ID = testTelegramSendMessageId
+		message.Unixtime = sentAt.Unix()
+
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      testCallbackId,
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+	}
+
+	expectDeleteConfirmation := func() {
+		NewGock().Times(1).Post("/deleteMessage").JSON(map[string]interface{}{
+			"chat_id":    testTelegramUserIdString,
+			"message_id": strconv.Itoa(testTelegramSendMessageId),
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+	}
+
+	t.Run("confirmation", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
 
 		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
 		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
 
+		replyMarkup := &tele.ReplyMarkup{
+			InlineKeyboard: [][]tele.InlineButton{
+				{
+					*telegramController.callbackButton(telegramController.markups.resetConfirmButton, CallbackPayload{}),
+					*telegramController.callbackButton(telegramController.markups.resetCancelButton, CallbackPayload{}),
+				},
+			},
+		}
+		ProcessReplyMarkup(replyMarkup)
+
+		delayedDeleter := telegramController.welcomeAnonymousDelayedDeleter.(*mocks.DeleterInterface)
+		delayedDeleter.On("AddToQueue", mock.MatchedBy(func(task *contracts.DeleteTask) bool {
+			expectedScheduledAt := time.Now().Add(resetConfirmationTTL).Unix()
+			return task.MessageId == testTelegramSendMessageId && task.ChatId == testTelegramUserId &&
+				task.ScheduledAt >= expectedScheduledAt-5 && task.ScheduledAt <= expectedScheduledAt
+		})).Return().Once()
+
 		defer gock.Off()
-		NewGock().Times(0)
+		NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(replyMarkup),
+			"text":         resetConfirmationText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
 
 		message := getTestSampleMessage()
 		message.Text = resetCommand
@@ -181,6 +317,69 @@ func TestTelegramController_ResetAction(t *testing.T) {
 		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
 		assert.NoError(t, lastTelegramErr)
 		assert.True(t, gock.IsDone())
+		telegramController.userLogoutHandler.(*mocks.UserLogoutHandlerInterface).AssertNotCalled(t, "Handle", mock.Anything)
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userLogoutHandler := telegramController.userLogoutHandler.(*mocks.UserLogoutHandlerInterface)
+		userLogoutHandler.On("Handle", testTelegramUserIdString).Return(nil).Once()
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		expectDeleteConfirmation()
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": testCallbackId,
+		}).Reply(200).JSON(answerCallbackSuccessResponse)
+
+		processResetButton(telegramController, telegramController.markups.resetConfirmButton, time.Now())
+
+		assert.NoError(t, lastTelegramErr)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		expectDeleteConfirmation()
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": testCallbackId,
+			"text":              resetCancelledText,
+		}).Reply(200).JSON(answerCallbackSuccessResponse)
+
+		processResetButton(telegramController, telegramController.markups.resetCancelButton, time.Now())
+
+		assert.NoError(t, lastTelegramErr)
+		assert.True(t, gock.IsDone())
+		telegramController.userLogoutHandler.(*mocks.UserLogoutHandlerInterface).AssertNotCalled(t, "Handle", mock.Anything)
+	})
+
+	t.Run("expired_confirmation", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(0).Post("/deleteMessage")
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": testCallbackId,
+			"text":              callbackExpiredText,
+			"show_alert":        true,
+		}).Reply(200).JSON(answerCallbackSuccessResponse)
+
+		processResetButton(telegramController, telegramController.markups.resetConfirmButton, time.Now().Add(-resetConfirmationTTL-time.Minute))
+
+		assert.NoError(t, lastTelegramErr)
+		assert.True(t, gock.IsDone())
+		telegramController.userLogoutHandler.(*mocks.UserLogoutHandlerInterface).AssertNotCalled(t, "Handle", mock.Anything)
 	})
 }
 
@@ -189,7 +388,7 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 		testAuthUrl := "http://auth.kneu.test/oauth"
 		expireAt := time.Date(2024, 3, 24, 16, 25, 0, 0, time.Local)
 		messageData := models.WelcomeAnonymousMessageData{
-			AuthUrl:  testAuthUrl,
+			AuthUrl:  markDownData(testAuthUrl),
 			ExpireAt: expireAt,
 		}
 
@@ -214,8 +413,8 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 
 		sendMessageRequest := map[string]interface{}{
 			"chat_id":         testTelegramUserIdString,
-			"parse_mode":      "Markdown",
-			"reply_markup":    toJson(telegramController.markups.logoutUserReplyMarkup),
+			"parse_mode":      string(testPref.ParseMode),
+			"reply_markup":    toJson(makeTestWelcomeReplyMarkup(telegramController, testAuthUrl)),
 			"protect_content": "true",
 			"text":            testMessageText,
 		}
@@ -237,7 +436,7 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 		testAuthUrl := "http://auth.kneu.test/oauth"
 		expireAt := time.Date(2024, 3, 24, 16, 25, 0, 0, time.Local)
 		messageData := models.WelcomeAnonymousMessageData{
-			AuthUrl:  testAuthUrl,
+			AuthUrl:  markDownData(testAuthUrl),
 			ExpireAt: expireAt,
 		}
 
@@ -254,8 +453,8 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 
 		sendMessageRequest := map[string]interface{}{
 			"chat_id":         testTelegramUserIdString,
-			"parse_mode":      "Markdown",
-			"reply_markup":    toJson(telegramController.markups.logoutUserReplyMarkup),
+			"parse_mode":      string(testPref.ParseMode),
+			"reply_markup":    toJson(makeTestWelcomeReplyMarkup(telegramController, testAuthUrl)),
 			"protect_content": "true",
 			"text":            testMessageText,
 		}
@@ -285,7 +484,7 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 		testAuthUrl := "http://auth.kneu.test/oauth"
 		expireAt := time.Date(2024, 3, 24, 16, 25, 0, 0, time.Local)
 		messageData := models.WelcomeAnonymousMessageData{
-			AuthUrl:  testAuthUrl,
+			AuthUrl:  markDownData(testAuthUrl),
 			ExpireAt: expireAt,
 		}
 		expectedError := errors.New("expected error")
@@ -328,6 +527,7 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 
 		out := &bytes.Buffer{}
 		telegramController.out = out
+		telegramController.logger = newLogger(out, logFormatText)
 
 		message := getTestSampleMessage()
 		message.Text = startCommand
@@ -339,6 +539,107 @@ func TestTelegramController_WelcomeAnonymousAction(t *testing.T) {
 		assert.True(t, gock.IsDone())
 	})
 
+	t.Run("refresh_auth_url", func(t *testing.T) {
+		testAuthUrl := "http://auth.kneu.test/oauth?refreshed"
+		previousExpireAt := time.Now().Add(time.Minute)
+		expireAt := time.Now().Add(time.Minute * 15)
+		messageData := models.WelcomeAnonymousMessageData{
+			AuthUrl:  markDownData(testAuthUrl),
+			ExpireAt: expireAt,
+		}
+
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(nil).Once()
+
+		authorizerClient := telegramController.authorizerClient.(*authorizerMocks.ClientInterface)
+		authorizerClient.On("GetAuthUrl", testTelegramUserIdString, "https://t.me/?start").Return(testAuthUrl, expireAt, nil).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeWelcomeAnonymousMessage", messageData).Return(nil, testMessageText).Once()
+
+		expectedTask := &contracts.DeleteTask{
+			ScheduledAt: expireAt.Unix(),
+			MessageId:   testTelegramSendMessageId,
+			ChatId:      testTelegramUserId,
+		}
+		delayedDeleter := telegramController.welcomeAnonymousDelayedDeleter.(*mocks.DeleterInterface)
+		delayedDeleter.On("AddToQueue", expectedTask).Return().Once()
+
+		defer gock.Off()
+		NewGock().Times(0).Post("/sendMessage")
+		NewGock().Times(1).Post("/editMessageText").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"message_id":   strconv.Itoa(testTelegramSendMessageId),
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(makeTestWelcomeReplyMarkup(telegramController, testAuthUrl)),
+			"text":         testMessageText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+		NewGock().Times(1).Post("/answerCallbackQuery").
+			Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		button := telegramController.callbackButton(telegramController.markups.refreshAuthUrlButton, CallbackPayload{})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
+		message.ID = testTelegramSendMessageId
+		message.Unixtime = time.Now().Unix()
+
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      "callback-auth-url",
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.NoError(t, lastTelegramErr)
+		assert.True(t, gock.IsDone())
+
+		// the task queued for the previous link should not delete the edited message
+		err := telegramController.HandleDeleteTask(&contracts.DeleteTask{
+			ScheduledAt: previousExpireAt.Unix(),
+			MessageId:   testTelegramSendMessageId,
+			ChatId:      testTelegramUserId,
+		})
+		assert.NoError(t, err)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("refresh_auth_url_authorized", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": "callback-auth-url",
+			"text":              alreadyAuthorizedText,
+			"show_alert":        true,
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		button := telegramController.callbackButton(telegramController.markups.refreshAuthUrlButton, CallbackPayload{})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
+		message.ID = testTelegramSendMessageId
+
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      "callback-auth-url",
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.NoError(t, lastTelegramErr)
+		assert.True(t, gock.IsDone())
+		telegramController.authorizerClient.(*authorizerMocks.ClientInterface).AssertNotCalled(t, "GetAuthUrl", mock.Anything, mock.Anything)
+	})
 }
 
 func TestTelegramController_HandleDeleteTask(t *testing.T) {
@@ -382,6 +683,33 @@ func TestTelegramController_HandleDeleteTask(t *testing.T) {
 		assert.Error(t, err)
 
 	})
+
+	t.Run("transientErrorRetry", func(t *testing.T) {
+		expectedTask := &contracts.DeleteTask{
+			ScheduledAt: time.Now().Unix(),
+			MessageId:   123456,
+			ChatId:      98789,
+		}
+
+		telegramController := CreateTelegramController(t)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/deleteMessage").
+			Reply(500).JSON(map[string]interface{}{
+			"ok":          false,
+			"error_code":  500,
+			"description": "Internal Server Error",
+		})
+		NewGock().Times(1).Post("/deleteMessage").
+			Reply(200).JSON(map[string]interface{}{
+			"ok":     true,
+			"result": true,
+		})
+
+		err := telegramController.HandleDeleteTask(expectedTask)
+		assert.NoError(t, err)
+		assert.True(t, gock.IsDone())
+	})
 }
 
 func TestTelegramController_WelcomeAuthorizedAction(t *testing.T) {
@@ -396,7 +724,7 @@ func TestTelegramController_WelcomeAuthorizedAction(t *testing.T) {
 
 	expectedJson := map[string]interface{}{
 		"chat_id":      testTelegramUserIdString,
-		"parse_mode":   "Markdown",
+		"parse_mode":   string(testPref.ParseMode),
 		"reply_markup": toJson(telegramController.markups.authorizedUserReplyMarkup),
 		"text":         testMessageText,
 	}
@@ -583,87 +911,304 @@ func TestTelegramController_WelcomeAuthorizedAction(t *testing.T) {
 		assert.Greater(t, duration, time.Duration(sendRetryCount)*time.Second)
 		assert.True(t, gock.IsDone())
 	})
-}
 
-func TestTelegramController_LogoutFinishedAction(t *testing.T) {
-	t.Run("success", func(t *testing.T) {
-		telegramController := CreateTelegramController(t)
+	badGatewayResponse := map[string]interface{}{
+		"ok":          false,
+		"error_code":  502,
+		"description": "Bad Gateway",
+	}
 
-		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
-		messageCompose.On("ComposeLogoutFinishedMessage").Return(nil, testMessageText)
+	t.Run("TransientErrorAndSuccessRetry", func(t *testing.T) {
+		userRepository := mocks.NewUserRepositoryInterface(t)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(&models.Student{}).Once()
 
-		expectedJson := map[string]interface{}{
-			"chat_id":      testTelegramUserIdString,
-			"parse_mode":   "Markdown",
-			"reply_markup": toJson(telegramController.markups.logoutUserReplyMarkup),
-			"text":         testMessageText,
-		}
+		messageCompose := mocks.NewMessageComposerInterface(t)
+		messageCompose.On("ComposeWelcomeAuthorizedMessage", messageData).Return(nil, testMessageText)
+
+		telegramController.composer = messageCompose
+		telegramController.userRepository = userRepository
+
+		retryTransientBefore := TelegramRetryTransientTotal.Get()
 
 		defer gock.Off()
-		NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
-			Reply(200).JSON(sendMessageSuccessResponse)
+		NewGock().Times(2).
+			Post("/sendMessage").JSON(expectedJson).
+			Reply(502).JSON(badGatewayResponse)
 
-		event := &events.UserAuthorizedEvent{
-			Client:       "test",
-			ClientUserId: testTelegramUserIdString,
-			StudentId:    0,
-			LastName:     "",
-			FirstName:    "",
-			MiddleName:   "",
-			Gender:       0,
-		}
+		NewGock().Times(1).
+			Post("/sendMessage").JSON(expectedJson).
+			Reply(200).JSON(sendMessageSuccessResponse)
 
-		err := telegramController.LogoutFinishedAction(event)
+		err := telegramController.WelcomeAuthorizedAction(event)
 
 		assert.NoError(t, err)
+		assert.Equal(t, retryTransientBefore+2, TelegramRetryTransientTotal.Get())
 		assert.True(t, gock.IsDone())
 	})
 
-	t.Run("error", func(t *testing.T) {
-		expectedError := errors.New("expected error")
+	t.Run("TransientErrorAndReachRetryLimit", func(t *testing.T) {
+		userRepository := mocks.NewUserRepositoryInterface(t)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(&models.Student{}).Once()
 
-		telegramController := CreateTelegramController(t)
+		messageCompose := mocks.NewMessageComposerInterface(t)
+		messageCompose.On("ComposeWelcomeAuthorizedMessage", messageData).Return(nil, testMessageText)
 
-		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
-		messageCompose.On("ComposeLogoutFinishedMessage").Return(expectedError, "")
+		telegramController.composer = messageCompose
+		telegramController.userRepository = userRepository
 
-		defer gock.Off()
-		NewGock().Times(0)
+		retryExhaustedBefore := TelegramCallRetryExhaustedTotal.Get()
 
-		event := &events.UserAuthorizedEvent{
-			Client:       "test",
-			ClientUserId: testTelegramUserIdString,
-			StudentId:    0,
-			LastName:     "",
-			FirstName:    "",
-			MiddleName:   "",
-			Gender:       0,
-		}
+		defer gock.Off()
+		NewGock().Times(sendRetryCount).
+			Post("/sendMessage").JSON(expectedJson).
+			Reply(502).JSON(badGatewayResponse)
 
-		err := telegramController.LogoutFinishedAction(event)
+		err := telegramController.WelcomeAuthorizedAction(event)
 
 		assert.Error(t, err)
-		assert.Equal(t, expectedError, err)
-
+		assert.ErrorContains(t, err, "(502)")
+		assert.Equal(t, retryExhaustedBefore+1, TelegramCallRetryExhaustedTotal.Get())
 		assert.True(t, gock.IsDone())
 	})
 
-	t.Run("error_send", func(t *testing.T) {
-		errorText := "Bad Request: can't parse entities"
-		expectedError := errors.New("telegram: " + errorText + " (400)")
+	t.Run("ReachRetryLimitWithoutFinalBackoff", func(t *testing.T) {
+		userRepository := mocks.NewUserRepositoryInterface(t)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(&models.Student{}).Once()
 
-		telegramController := CreateTelegramController(t)
-		out := telegramController.out.(*bytes.Buffer)
+		messageCompose := mocks.NewMessageComposerInterface(t)
+		messageCompose.On("ComposeWelcomeAuthorizedMessage", messageData).Return(nil, testMessageText)
 
-		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
-		messageCompose.On("ComposeLogoutFinishedMessage").Return(nil, testMessageText)
+		telegramController.composer = messageCompose
+		telegramController.userRepository = userRepository
 
-		expectedJson := map[string]interface{}{
-			"chat_id":      testTelegramUserIdString,
-			"parse_mode":   "Markdown",
-			"reply_markup": toJson(telegramController.markups.logoutUserReplyMarkup),
-			"text":         testMessageText,
-		}
+		retryPolicy := telegramController.retryPolicy
+		defer func() {
+			telegramController.retryPolicy = retryPolicy
+		}()
+		telegramController.retryPolicy = &RetryPolicy{maxAttempts: 1, baseDelay: time.Minute, maxDelay: time.Minute}
+
+		retryExhaustedBefore := TelegramCallRetryExhaustedTotal.Get()
+
+		defer gock.Off()
+		NewGock().Times(1).
+			Post("/sendMessage").JSON(expectedJson).
+			Reply(502).JSON(badGatewayResponse)
+
+		start := time.Now()
+		err := telegramController.WelcomeAuthorizedAction(event)
+
+		assert.ErrorContains(t, err, "(502)")
+		assert.Less(t, time.Since(start), time.Second*10)
+		assert.Equal(t, retryExhaustedBefore+1, TelegramCallRetryExhaustedTotal.Get())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("PermanentErrorNoRetry", func(t *testing.T) {
+		userRepository := mocks.NewUserRepositoryInterface(t)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(&models.Student{}).Once()
+
+		messageCompose := mocks.NewMessageComposerInterface(t)
+		messageCompose.On("ComposeWelcomeAuthorizedMessage", messageData).Return(nil, testMessageText)
+
+		telegramController.composer = messageCompose
+		telegramController.userRepository = userRepository
+
+		permanentErrorBefore := TelegramCallPermanentErrorTotal.Get()
+
+		defer gock.Off()
+		NewGock().Times(1).
+			Post("/sendMessage").JSON(expectedJson).
+			Reply(400).JSON(map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": "Bad Request: chat not found",
+		})
+
+		err := telegramController.WelcomeAuthorizedAction(event)
+
+		assert.ErrorIs(t, err, tele.ErrChatNotFound)
+		assert.Equal(t, permanentErrorBefore+1, TelegramCallPermanentErrorTotal.Get())
+		assert.True(t, gock.IsDone())
+	})
+}
+
+func TestTelegramController_SendCancellation(t *testing.T) {
+	event := &events.UserAuthorizedEvent{
+		Client:       "test",
+		ClientUserId: testTelegramUserIdString,
+	}
+
+	floodErrorResponse := map[string]interface{}{
+		"ok":          false,
+		"error_code":  429,
+		"description": "Too Many Requests: retry after 60",
+		"parameters": map[string]interface{}{
+			"retry_after": 60,
+		},
+	}
+
+	t.Run("shutdownDuringFloodRetry", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(&models.Student{}).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeWelcomeAuthorizedMessage", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(429).JSON(floodErrorResponse)
+
+		go func() {
+			time.Sleep(time.Millisecond * 100)
+			telegramController.cancel()
+		}()
+
+		start := time.Now()
+		err := telegramController.WelcomeAuthorizedAction(event)
+		duration := time.Since(start)
+
+		assert.ErrorIs(t, err, ErrSendCancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, duration, time.Second)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("shutdownWhileRateLimited", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.rateLimiter = rate.NewLimiter(rate.Every(time.Minute), 1)
+		telegramController.rateLimiter.Allow()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeLogoutFinishedMessage").Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		go func() {
+			time.Sleep(time.Millisecond * 100)
+			telegramController.cancel()
+		}()
+
+		start := time.Now()
+		err := telegramController.LogoutFinishedAction(event)
+		duration := time.Since(start)
+
+		assert.ErrorIs(t, err, ErrSendCancelled)
+		assert.Less(t, duration, time.Second)
+		assert.Empty(t, telegramController.out.(*bytes.Buffer).String())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("cancelledScoreChangedActionContext", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		disciplineScore := &scoreApi.DisciplineScore{
+			Discipline: scoreApi.Discipline{Id: 12, Name: "Капітал!"},
+			Score:      scoreApi.Score{FirstScore: floatPointer(2.5)},
+		}
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(429).JSON(floodErrorResponse)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+
+		start := time.Now()
+		err, messageId := telegramController.ScoreChangedActionContext(
+			ctx, testTelegramUserIdString, "", disciplineScore, &scoreApi.Score{},
+		)
+
+		assert.ErrorIs(t, err, ErrSendCancelled)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Empty(t, messageId)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.True(t, gock.IsDone())
+	})
+}
+
+func TestTelegramController_LogoutFinishedAction(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeLogoutFinishedMessage").Return(nil, testMessageText)
+
+		expectedJson := map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(telegramController.markups.logoutUserReplyMarkup),
+			"text":         testMessageText,
+		}
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
+			Reply(200).JSON(sendMessageSuccessResponse)
+
+		event := &events.UserAuthorizedEvent{
+			Client:       "test",
+			ClientUserId: testTelegramUserIdString,
+			StudentId:    0,
+			LastName:     "",
+			FirstName:    "",
+			MiddleName:   "",
+			Gender:       0,
+		}
+
+		err := telegramController.LogoutFinishedAction(event)
+
+		assert.NoError(t, err)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		telegramController := CreateTelegramController(t)
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeLogoutFinishedMessage").Return(expectedError, "")
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		event := &events.UserAuthorizedEvent{
+			Client:       "test",
+			ClientUserId: testTelegramUserIdString,
+			StudentId:    0,
+			LastName:     "",
+			FirstName:    "",
+			MiddleName:   "",
+			Gender:       0,
+		}
+
+		err := telegramController.LogoutFinishedAction(event)
+
+		assert.Error(t, err)
+		assert.Equal(t, expectedError, err)
+
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("error_send", func(t *testing.T) {
+		errorText := "Bad Request: can't parse entities"
+		expectedError := errors.New("telegram: " + errorText + " (400)")
+
+		telegramController := CreateTelegramController(t)
+		out := telegramController.out.(*bytes.Buffer)
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeLogoutFinishedMessage").Return(nil, testMessageText)
+
+		expectedJson := map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(telegramController.markups.logoutUserReplyMarkup),
+			"text":         testMessageText,
+		}
 
 		defer gock.Off()
 		NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
@@ -674,6 +1219,19 @@ func TestTelegramController_LogoutFinishedAction(t *testing.T) {
 				"description": errorText,
 			})
 
+		plainTextJson := map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"reply_markup": toJson(telegramController.markups.logoutUserReplyMarkup),
+			"text":         testMessageText,
+		}
+		NewGock().Times(1).Post("/sendMessage").JSON(plainTextJson).
+			Reply(400).
+			JSON(map[string]interface{}{
+				"ok":          false,
+				"error_code":  400,
+				"description": errorText,
+			})
+
 		event := &events.UserAuthorizedEvent{
 			Client:       "test",
 			ClientUserId: testTelegramUserIdString,
@@ -735,9 +1293,9 @@ func TestTelegramController_DisciplinesListAction(t *testing.T) {
 		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
 
 		messageData := models.DisciplinesListMessageData{
-			StudentMessageData: models.NewStudentMessageData(sampleStudent),
-			Disciplines:        disciplines,
-			SupportInfo:        SupportInfo,
+			StudentMessageData: markDownStudentMessageData(sampleStudent),
+			Disciplines:        markDownDisciplineScoreResults(disciplines),
+			SupportInfo:        markDownData(SupportInfo),
 		}
 
 		replyMarkup := &tele.ReplyMarkup{
@@ -748,7 +1306,7 @@ func TestTelegramController_DisciplinesListAction(t *testing.T) {
 			replyMarkup.InlineKeyboard[i] = []tele.InlineButton{
 				{
 					Unique: telegramController.markups.disciplineButton.Unique,
-					Data:   strconv.Itoa(discipline.Discipline.Id),
+					Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: discipline.Discipline.Id}),
 					Text:   discipline.Discipline.Name,
 				},
 			}
@@ -757,7 +1315,7 @@ func TestTelegramController_DisciplinesListAction(t *testing.T) {
 
 		expectedMessageSend := map[string]interface{}{
 			"chat_id":      testTelegramUserIdString,
-			"parse_mode":   "Markdown",
+			"parse_mode":   string(testPref.ParseMode),
 			"reply_markup": toJson(replyMarkup),
 			"text":         testMessageText,
 		}
@@ -769,17 +1327,21 @@ func TestTelegramController_DisciplinesListAction(t *testing.T) {
 		NewGock().Times(1).Post("/sendMessage").JSON(expectedMessageSend).
 			Reply(200).JSON(sendMessageSuccessResponse)
 
+		actionBefore := histogramCount(ActionDurationHistogram("DisciplinesListAction", nil))
+		sendBefore := histogramCount(TelegramApiDurationHistogram("sendMessage", sendErrorNone))
+
 		message := getTestSampleMessage()
 		message.Text = listCommand
 
 		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
 
 		assert.True(t, gock.IsDone())
+		assert.Equal(t, actionBefore+1, histogramCount(ActionDurationHistogram("DisciplinesListAction", nil)))
+		assert.Equal(t, sendBefore+1, histogramCount(TelegramApiDurationHistogram("sendMessage", sendErrorNone)))
 	})
 
-	t.Run("error", func(t *testing.T) {
+	t.Run("list_button_edit", func(t *testing.T) {
 		telegramController := CreateTelegramController(t)
-		expectedError := errors.New("expected error")
 
 		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
 		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
@@ -787,118 +1349,389 @@ func TestTelegramController_DisciplinesListAction(t *testing.T) {
 		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
 		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
 
-		messageData := models.DisciplinesListMessageData{
-			StudentMessageData: models.NewStudentMessageData(sampleStudent),
-			Disciplines:        disciplines,
-			SupportInfo:        SupportInfo,
-		}
-
 		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
-		messageCompose.On("ComposeDisciplinesListMessage", messageData).Return(expectedError, "")
+		messageCompose.On("ComposeDisciplinesListMessage", mock.Anything).Return(nil, testMessageText)
+
+		replyMarkup := telegramController.makeDisciplinesReplyMarkup(disciplines, 0)
+		ProcessReplyMarkup(replyMarkup)
 
 		defer gock.Off()
-		NewGock().Post("/sendMessage").Times(0)
+		NewGock().Times(0).Post("/sendMessage")
+		NewGock().Times(1).Post("/editMessageText").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"message_id":   strconv.Itoa(testTelegramSendMessageId),
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(replyMarkup),
+			"text":         testMessageText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		button := *telegramController.callbackButton(telegramController.markups.listButton, CallbackPayload{})
+		ProcessInlineButton(&button)
 
 		message := getTestSampleMessage()
-		message.Text = listCommand
+		message.ID = testTelegramSendMessageId
+		message.Unixtime = time.Now().Unix()
 
-		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
 
-		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
 		assert.True(t, gock.IsDone())
 	})
-}
-
-func TestTelegramController_DisciplineScoresAction(t *testing.T) {
-	disciplineId := 199
-
-	discipline := scoreApi.DisciplineScoreResult{
-		Discipline: scoreApi.Discipline{
-			Id:   disciplineId,
-			Name: "Капітал!",
-		},
-		ScoreRating: scoreApi.ScoreRating{
-			Total:         17,
-			StudentsCount: 25,
-			Rating:        8,
-			MinTotal:      10,
-			MaxTotal:      20,
-		},
-		Scores: []scoreApi.Score{
-			{
-				Lesson: scoreApi.Lesson{
-					Id:   245,
-					Date: time.Date(2023, time.Month(2), 12, 0, 0, 0, 0, time.Local),
-					Type: scoreApi.LessonType{
-						Id:        5,
-						ShortName: "МК",
-						LongName:  "Модульний контроль.",
-					},
-				},
-				FirstScore: floatPointer(4.5),
-				IsAbsent:   true,
-			},
-		},
-	}
 
-	t.Run("success", func(t *testing.T) {
+	t.Run("pagination", func(t *testing.T) {
 		telegramController := CreateTelegramController(t)
+		telegramController.disciplinesPageSize = 1
 
 		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
 		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
 
 		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
-		scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).Return(discipline, nil)
-
-		messageData := models.DisciplinesScoresMessageData{
-			StudentMessageData: models.NewStudentMessageData(sampleStudent),
-			Discipline:         discipline,
-		}
-		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
-		messageCompose.On("ComposeDisciplineScoresMessage", messageData).Return(nil, testMessageText)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
 
 		replyMarkup := &tele.ReplyMarkup{
 			OneTimeKeyboard: true,
 			InlineKeyboard: [][]tele.InlineButton{
 				{
-					*telegramController.markups.listButton,
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 100}),
+						Text:   "Капітал!",
+					},
+				},
+				{
+					{
+						Unique: "page",
+						Data:   telegramController.callbackCodec.Encode("page", CallbackPayload{Page: 1}),
+						Text:   "▶",
+					},
 				},
 			},
 		}
 		ProcessReplyMarkup(replyMarkup)
 
-		expectedJson := map[string]interface{}{
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeDisciplinesListMessage", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
 			"chat_id":      testTelegramUserIdString,
-			"parse_mode":   "Markdown",
+			"parse_mode":   string(testPref.ParseMode),
 			"reply_markup": toJson(replyMarkup),
 			"text":         testMessageText,
-		}
+		}).Reply(200).JSON(sendMessageSuccessResponse)
 
-		defer gock.Off()
-		NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
-			Reply(200).JSON(sendMessageSuccessResponse)
+		message := getTestSampleMessage()
+		message.Text = listCommand
+
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("two_columns", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.disciplinesTwoColumns = true
+
+		longNameDiscipline := scoreApi.DisciplineScoreResult{
+			Discipline: scoreApi.Discipline{
+				Id:   120,
+				Name: "Економіка праці й соціально-трудові відносини",
+			},
+		}
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).
+			Return(append(disciplines[:2:2], longNameDiscipline), nil)
+
+		replyMarkup := &tele.ReplyMarkup{
+			OneTimeKeyboard: true,
+			InlineKeyboard: [][]tele.InlineButton{
+				{
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 100}),
+						Text:   "Капітал!",
+					},
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 110}),
+						Text:   "Гроші та лихварство",
+					},
+				},
+				{
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 120}),
+						Text:   longNameDiscipline.Discipline.Name,
+					},
+				},
+			},
+		}
+		ProcessReplyMarkup(replyMarkup)
 
-		cbData := fmt.Sprintf(`%s|%d`, telegramController.markups.disciplineButton.CallbackUnique(), disciplineId)
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeDisciplinesListMessage", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(replyMarkup),
+			"text":         testMessageText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
 
 		message := getTestSampleMessage()
-		message.Text = ""
+		message.Text = listCommand
+
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("score_service_unavailable", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		expectedError := errors.New("expected error")
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(nil, expectedError)
+
+		replyMarkup := &tele.ReplyMarkup{
+			InlineKeyboard: [][]tele.InlineButton{
+				{*telegramController.callbackButton(telegramController.markups.retryListButton, CallbackPayload{})},
+			},
+		}
+		ProcessReplyMarkup(replyMarkup)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(replyMarkup),
+			"text":         scoreServiceUnavailableMessage,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		message := getTestSampleMessage()
+		message.Text = listCommand
+
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		expectedError := errors.New("expected error")
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
+
+		messageData := models.DisciplinesListMessageData{
+			StudentMessageData: markDownStudentMessageData(sampleStudent),
+			Disciplines:        markDownDisciplineScoreResults(disciplines),
+			SupportInfo:        markDownData(SupportInfo),
+		}
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeDisciplinesListMessage", messageData).Return(expectedError, "")
+
+		defer gock.Off()
+		NewGock().Post("/sendMessage").Times(0)
+
+		message := getTestSampleMessage()
+		message.Text = listCommand
+
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+		assert.True(t, gock.IsDone())
+	})
+}
 
+func TestTelegramController_DisciplinesPageAction(t *testing.T) {
+	disciplines := scoreApi.DisciplineScoreResults{
+		{Discipline: scoreApi.Discipline{Id: 100, Name: "Капітал!"}},
+		{Discipline: scoreApi.Discipline{Id: 110, Name: "Гроші та лихварство"}},
+		{Discipline: scoreApi.Discipline{Id: 120, Name: "Фінанси"}},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.disciplinesPageSize = 1
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
+
+		replyMarkup := &tele.ReplyMarkup{
+			OneTimeKeyboard: true,
+			InlineKeyboard: [][]tele.InlineButton{
+				{
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 110, Page: 1}),
+						Text:   "Гроші та лихварство",
+					},
+				},
+				{
+					{Unique: "page", Data: telegramController.callbackCodec.Encode("page", CallbackPayload{}), Text: "◀"},
+					{Unique: "page", Data: telegramController.callbackCodec.Encode("page", CallbackPayload{Page: 2}), Text: "▶"},
+				},
+			},
+		}
+		ProcessReplyMarkup(replyMarkup)
+
+		defer gock.Off()
+		NewGock().Times(0).Post("/sendMessage")
+		NewGock().Times(1).Post("/editMessageReplyMarkup").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"message_id":   strconv.Itoa(testTelegramIncomingMessageId),
+			"reply_markup": toJson(replyMarkup),
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		button := telegramController.callbackButton(telegramController.markups.nextPageButton, CallbackPayload{Page: 1})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
 		telegramController.bot.ProcessUpdate(tele.Update{
-			Message: &message,
 			Callback: &tele.Callback{
-				Data:   cbData,
-				Sender: message.Sender,
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
 			},
 		})
 
 		assert.True(t, gock.IsDone())
 	})
 
-	t.Run("failedToParseEntityError", func(t *testing.T) {
+	t.Run("last_page_out_of_range", func(t *testing.T) {
 		telegramController := CreateTelegramController(t)
+		telegramController.disciplinesPageSize = 2
 
-		out := &bytes.Buffer{}
-		telegramController.out = out
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(disciplines, nil)
+
+		replyMarkup := &tele.ReplyMarkup{
+			OneTimeKeyboard: true,
+			InlineKeyboard: [][]tele.InlineButton{
+				{
+					{
+						Unique: telegramController.markups.disciplineButton.Unique,
+						Data:   telegramController.callbackCodec.Encode("discipline", CallbackPayload{DisciplineId: 120, Page: 1}),
+						Text:   "Фінанси",
+					},
+				},
+				{
+					{Unique: "page", Data: telegramController.callbackCodec.Encode("page", CallbackPayload{}), Text: "◀"},
+				},
+			},
+		}
+		ProcessReplyMarkup(replyMarkup)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/editMessageReplyMarkup").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"message_id":   strconv.Itoa(testTelegramIncomingMessageId),
+			"reply_markup": toJson(replyMarkup),
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		button := telegramController.callbackButton(telegramController.markups.nextPageButton, CallbackPayload{Page: 7})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		expectedError := errors.New("expected error")
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(nil, expectedError)
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		button := telegramController.callbackButton(telegramController.markups.nextPageButton, CallbackPayload{Page: 1})
+		ProcessInlineButton(button)
+
+		message := getTestSampleMessage()
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+	})
+}
+
+func TestTelegramController_DisciplineScoresAction(t *testing.T) {
+	disciplineId := 199
+
+	discipline := scoreApi.DisciplineScoreResult{
+		Discipline: scoreApi.Discipline{
+			Id:   disciplineId,
+			Name: "Капітал!",
+		},
+		ScoreRating: scoreApi.ScoreRating{
+			Total:         17,
+			StudentsCount: 25,
+			Rating:        8,
+			MinTotal:      10,
+			MaxTotal:      20,
+		},
+		Scores: []scoreApi.Score{
+			{
+				Lesson: scoreApi.Lesson{
+					Id:   245,
+					Date: time.Date(2023, time.Month(2), 12, 0, 0, 0, 0, time.Local),
+					Type: scoreApi.LessonType{
+						Id:        5,
+						ShortName: "МК",
+						LongName:  "Модульний контроль.",
+					},
+				},
+				FirstScore: floatPointer(4.5),
+				IsAbsent:   true,
+			},
+		},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
 
 		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
 		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
@@ -907,8 +1740,8 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 		scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).Return(discipline, nil)
 
 		messageData := models.DisciplinesScoresMessageData{
-			StudentMessageData: models.NewStudentMessageData(sampleStudent),
-			Discipline:         discipline,
+			StudentMessageData: markDownStudentMessageData(sampleStudent),
+			Discipline:         markDownDisciplineScoreResult(discipline),
 		}
 		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
 		messageCompose.On("ComposeDisciplineScoresMessage", messageData).Return(nil, testMessageText)
@@ -917,7 +1750,7 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 			OneTimeKeyboard: true,
 			InlineKeyboard: [][]tele.InlineButton{
 				{
-					*telegramController.markups.listButton,
+					*telegramController.callbackButton(telegramController.markups.listButton, CallbackPayload{}),
 				},
 			},
 		}
@@ -925,23 +1758,16 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 
 		expectedJson := map[string]interface{}{
 			"chat_id":      testTelegramUserIdString,
-			"parse_mode":   "Markdown",
+			"parse_mode":   string(testPref.ParseMode),
 			"reply_markup": toJson(replyMarkup),
 			"text":         testMessageText,
 		}
 
-		errorText := "Bad Request: can't parse entities: Can't find end of Underline entity at byte offset 143"
-
 		defer gock.Off()
 		NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
-			Reply(400).
-			JSON(map[string]interface{}{
-				"ok":          false,
-				"error_code":  400,
-				"description": errorText,
-			})
+			Reply(200).JSON(sendMessageSuccessResponse)
 
-		cbData := fmt.Sprintf(`%s|%d`, telegramController.markups.disciplineButton.CallbackUnique(), disciplineId)
+		cbData := makeTestCallbackData(telegramController, telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineId})
 
 		message := getTestSampleMessage()
 		message.Text = ""
@@ -955,11 +1781,269 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 		})
 
 		assert.True(t, gock.IsDone())
-		assert.Error(t, GetEndClearLastTelegramError())
+	})
+
+	t.Run("edit_in_place", func(t *testing.T) {
+		runEditFlow := func(t *testing.T, viewMode int, callbackMessage *tele.Message, mockTelegram func(replyMarkup *tele.ReplyMarkup)) {
+			telegramController := CreateTelegramController(t)
+
+			userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+			userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+			scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+			scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).Return(discipline, nil)
+
+			messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+			messageCompose.On("ComposeDisciplineScoresMessage", mock.Anything).Return(nil, testMessageText)
+
+			replyMarkup := &tele.ReplyMarkup{
+				OneTimeKeyboard: true,
+				InlineKeyboard: [][]tele.InlineButton{
+					{
+						*telegramController.callbackButton(telegramController.markups.listButton, CallbackPayload{}),
+					},
+				},
+			}
+			ProcessReplyMarkup(replyMarkup)
+
+			defer gock.Off()
+			mockTelegram(replyMarkup)
+
+			button := telegramController.callbackButton(telegramController.markups.disciplineButton, CallbackPayload{
+				DisciplineId: disciplineId,
+				ViewMode:     viewMode,
+			})
+			ProcessInlineButton(button)
+
+			telegramController.bot.ProcessUpdate(tele.Update{
+				Callback: &tele.Callback{
+					Message: callbackMessage,
+					Data:    button.Data,
+					Sender:  callbackMessage.Sender,
+				},
+			})
+
+			assert.True(t, gock.IsDone())
+		}
+
+		makeCallbackMessage := func(sentAt time.Time) *tele.Message {
+			message := getTestSampleMessage()
+			message.ID = testTelegramSendMessageId
+			message.Unixtime = sentAt.Unix()
+			return &message
+		}
+
+		expectedEdit := func(replyMarkup *tele.ReplyMarkup) map[string]interface{} {
+			return map[string]interface{}{
+				"chat_id":      testTelegramUserIdString,
+				"message_id":   strconv.Itoa(testTelegramSendMessageId),
+				"parse_mode":   string(testPref.ParseMode),
+				"reply_markup": toJson(replyMarkup),
+				"text":         testMessageText,
+			}
+		}
+
+		expectedSend := func(replyMarkup *tele.ReplyMarkup) map[string]interface{} {
+			return map[string]interface{}{
+				"chat_id":      testTelegramUserIdString,
+				"parse_mode":   string(testPref.ParseMode),
+				"reply_markup": toJson(replyMarkup),
+				"text":         testMessageText,
+			}
+		}
+
+		t.Run("edited", func(t *testing.T) {
+			runEditFlow(t, 0, makeCallbackMessage(time.Now()), func(replyMarkup *tele.ReplyMarkup) {
+				NewGock().Times(0).Post("/sendMessage")
+				NewGock().Times(1).Post("/editMessageText").JSON(expectedEdit(replyMarkup)).
+					Reply(200).JSON(sendMessageSuccessResponse)
+			})
+		})
+
+		t.Run("not_modified", func(t *testing.T) {
+			runEditFlow(t, 0, makeCallbackMessage(time.Now()), func(replyMarkup *tele.ReplyMarkup) {
+				NewGock().Times(0).Post("/sendMessage")
+				NewGock().Times(1).Post("/editMessageText").JSON(expectedEdit(replyMarkup)).
+					Reply(400).JSON(map[string]interface{}{
+					"ok":          false,
+					"error_code":  400,
+					"description": tele.ErrSameMessageContent.Description,
+				})
+			})
+		})
 
-		outString := out.String()
-		expectedOut := "DisciplineScoresAction failed to send message: telegram: " + errorText + " (400); text: test-message ! 0101\n"
-		assert.Equal(t, expectedOut, outString)
+		t.Run("deleted", func(t *testing.T) {
+			runEditFlow(t, 0, makeCallbackMessage(time.Now()), func(replyMarkup *tele.ReplyMarkup) {
+				NewGock().Times(1).Post("/editMessageText").JSON(expectedEdit(replyMarkup)).
+					Reply(400).JSON(map[string]interface{}{
+					"ok":          false,
+					"error_code":  400,
+					"description": "Bad Request: message to edit not found",
+				})
+				NewGock().Times(1).Post("/sendMessage").JSON(expectedSend(replyMarkup)).
+					Reply(200).JSON(sendMessageSuccessResponse)
+			})
+		})
+
+		t.Run("too_old", func(t *testing.T) {
+			runEditFlow(t, 0, makeCallbackMessage(time.Now().Add(-editableMessageMaxAge-time.Minute)), func(replyMarkup *tele.ReplyMarkup) {
+				NewGock().Times(0).Post("/editMessageText")
+				NewGock().Times(1).Post("/sendMessage").JSON(expectedSend(replyMarkup)).
+					Reply(200).JSON(sendMessageSuccessResponse)
+			})
+		})
+
+		t.Run("notification", func(t *testing.T) {
+			runEditFlow(t, viewModeNotification, makeCallbackMessage(time.Now()), func(replyMarkup *tele.ReplyMarkup) {
+				NewGock().Times(0).Post("/editMessageText")
+				NewGock().Times(1).Post("/sendMessage").JSON(expectedSend(replyMarkup)).
+					Reply(200).JSON(sendMessageSuccessResponse)
+			})
+		})
+	})
+
+	t.Run("long_message", func(t *testing.T) {
+		for _, parseMode := range testParseModes {
+			t.Run(string(parseMode), func(t *testing.T) {
+				telegramController := CreateTelegramControllerWithParseMode(t, parseMode)
+
+				userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+				userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+				scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+				scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).Return(discipline, nil)
+
+				firstPart := strings.Repeat("a", 4000)
+				lastPart := strings.Repeat("b", 200)
+
+				messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+				messageCompose.On("ComposeDisciplineScoresMessage", mock.Anything).Return(nil, firstPart+"\n"+lastPart)
+
+				replyMarkup := &tele.ReplyMarkup{
+					OneTimeKeyboard: true,
+					InlineKeyboard: [][]tele.InlineButton{
+						{
+							*telegramController.callbackButton(telegramController.markups.listButton, CallbackPayload{}),
+						},
+					},
+				}
+				ProcessReplyMarkup(replyMarkup)
+
+				defer gock.Off()
+				NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+					"chat_id":    testTelegramUserIdString,
+					"parse_mode": string(parseMode),
+					"text":       firstPart,
+				}).Reply(200).JSON(sendMessageSuccessResponse)
+
+				NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+					"chat_id":      testTelegramUserIdString,
+					"parse_mode":   string(parseMode),
+					"reply_markup": toJson(replyMarkup),
+					"text":         lastPart,
+				}).Reply(200).JSON(sendMessageSuccessResponse)
+
+				cbData := makeTestCallbackData(telegramController, telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineId})
+
+				message := getTestSampleMessage()
+				message.Text = ""
+
+				telegramController.bot.ProcessUpdate(tele.Update{
+					Message: &message,
+					Callback: &tele.Callback{
+						Data:   cbData,
+						Sender: message.Sender,
+					},
+				})
+
+				assert.True(t, gock.IsDone())
+			})
+		}
+	})
+
+	t.Run("failedToParseEntityError", func(t *testing.T) {
+		for _, parseMode := range testParseModes {
+			t.Run(string(parseMode), func(t *testing.T) {
+				telegramController := CreateTelegramControllerWithParseMode(t, parseMode)
+
+				out := &bytes.Buffer{}
+				telegramController.out = out
+				telegramController.logger = newLogger(out, logFormatText)
+
+				userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+				userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+				scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+				scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).Return(discipline, nil)
+
+				messageData := models.DisciplinesScoresMessageData{
+					StudentMessageData: markDownStudentMessageData(sampleStudent),
+					Discipline:         markDownDisciplineScoreResult(discipline),
+				}
+				messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+				messageCompose.On("ComposeDisciplineScoresMessage", messageData).Return(nil, testMessageText)
+
+				replyMarkup := &tele.ReplyMarkup{
+					OneTimeKeyboard: true,
+					InlineKeyboard: [][]tele.InlineButton{
+						{
+							*telegramController.callbackButton(telegramController.markups.listButton, CallbackPayload{}),
+						},
+					},
+				}
+				ProcessReplyMarkup(replyMarkup)
+
+				expectedJson := map[string]interface{}{
+					"chat_id":      testTelegramUserIdString,
+					"parse_mode":   string(parseMode),
+					"reply_markup": toJson(replyMarkup),
+					"text":         testMessageText,
+				}
+
+				errorText := "Bad Request: can't parse entities: Can't find end of Underline entity at byte offset 143"
+
+				defer gock.Off()
+				NewGock().Times(1).Post("/sendMessage").JSON(expectedJson).
+					Reply(400).
+					JSON(map[string]interface{}{
+						"ok":          false,
+						"error_code":  400,
+						"description": errorText,
+					})
+
+				plainTextJson := map[string]interface{}{
+					"chat_id":      testTelegramUserIdString,
+					"reply_markup": toJson(replyMarkup),
+					"text":         testMessageText,
+				}
+				NewGock().Times(1).Post("/sendMessage").JSON(plainTextJson).
+					Reply(200).JSON(sendMessageSuccessResponse)
+
+				fallbackCountBefore := ParseEntitiesErrorCount.Get()
+
+				cbData := makeTestCallbackData(telegramController, telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineId})
+
+				message := getTestSampleMessage()
+				message.Text = ""
+
+				telegramController.bot.ProcessUpdate(tele.Update{
+					Message: &message,
+					Callback: &tele.Callback{
+						Data:   cbData,
+						Sender: message.Sender,
+					},
+				})
+
+				assert.True(t, gock.IsDone())
+				assert.NoError(t, GetEndClearLastTelegramError())
+				assert.Equal(t, fallbackCountBefore+1, ParseEntitiesErrorCount.Get())
+
+				outString := out.String()
+				assert.Contains(t, outString, `level=WARN msg="Failed to parse entities, resend as plain text"`)
+				assert.Contains(t, outString, `error="telegram: `+errorText+` (400)" telegram_error_code=400`)
+				assert.Contains(t, outString, `text="test-message ! 0101"`)
+			})
+		}
 	})
 
 	t.Run("messageNotModifiedError", func(t *testing.T) {
@@ -974,6 +2058,7 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 
 		out := &bytes.Buffer{}
 		telegramController.out = out
+		telegramController.logger = newLogger(out, logFormatText)
 
 		expectedJson := map[string]interface{}{
 			"chat_id":      testTelegramUserIdString,
@@ -993,23 +2078,314 @@ func TestTelegramController_DisciplineScoresAction(t *testing.T) {
 				"description": "Bad Request: message is not modified",
 			})
 
-		button := telegramController.markups.disciplineButton.With(strconv.Itoa(disciplineId))
+		button := telegramController.callbackButton(telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineId})
 		ProcessInlineButton(button)
 
-		message := getTestSampleMessage()
-		message.Text = ""
+		message := getTestSampleMessage()
+		message.Text = ""
+
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Message: &message,
+			Callback: &tele.Callback{
+				Data:   button.Data,
+				Sender: message.Sender,
+			},
+		})
+
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+		assert.True(t, gock.IsDone())
+		assert.Contains(t, out.String(), `msg="Failed to remove reply markup" error="telegram: message is not modified (400)"`)
+	})
+}
+
+func TestTelegramController_RespondCallback(t *testing.T) {
+	disciplineId := 199
+	testCallbackId := "callback-123"
+
+	runCallbackFlow := func(t *testing.T, telegramController *TelegramController, expectedResponse map[string]interface{}) {
+		button := telegramController.callbackButton(telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineId})
+		ProcessInlineButton(button)
+
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(expectedResponse).
+			Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		message := getTestSampleMessage()
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      testCallbackId,
+				Message: &message,
+				Data:    button.Data,
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.True(t, gock.IsDone())
+	}
+
+	t.Run("success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).
+			Return(scoreApi.DisciplineScoreResult{}, nil)
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeDisciplineScoresMessage", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(200).JSON(sendMessageSuccessResponse)
+
+		runCallbackFlow(t, telegramController, map[string]interface{}{
+			"callback_query_id": testCallbackId,
+		})
+	})
+
+	t.Run("score_service_unavailable", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		expectedError := errors.New("expected error")
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).
+			Return(scoreApi.DisciplineScoreResult{}, expectedError)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/editMessageReplyMarkup").Reply(200).JSON(sendMessageSuccessResponse)
+
+		runCallbackFlow(t, telegramController, map[string]interface{}{
+			"callback_query_id": testCallbackId,
+			"text":              scoreServiceUnavailableText,
+			"show_alert":        true,
+		})
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		expectedError := errors.New("expected error")
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDiscipline", sampleStudent.Id, disciplineId).
+			Return(scoreApi.DisciplineScoreResult{}, nil)
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeDisciplineScoresMessage", mock.Anything).Return(expectedError, "")
+
+		defer gock.Off()
+
+		runCallbackFlow(t, telegramController, map[string]interface{}{
+			"callback_query_id": testCallbackId,
+			"text":              callbackErrorText,
+		})
+		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+	})
+
+	t.Run("expired_payload", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": testCallbackId,
+			"text":              callbackExpiredText,
+			"show_alert":        true,
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		message := getTestSampleMessage()
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      testCallbackId,
+				Message: &message,
+				Data:    "\f" + telegramController.markups.disciplineButton.Unique + "|" + strconv.Itoa(disciplineId),
+				Sender:  message.Sender,
+			},
+		})
+
+		assert.True(t, gock.IsDone())
+		telegramController.scoreClient.(*scoreMocks.ClientInterface).AssertNotCalled(t, "GetStudentDiscipline", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTelegramController_RecoverPanic(t *testing.T) {
+	expectedApologyMessage := map[string]interface{}{
+		"chat_id":    testTelegramUserIdString,
+		"parse_mode": string(testPref.ParseMode),
+		"text":       panicApologyText,
+	}
+
+	assertPanicError := func(t *testing.T, panicCountBefore uint64) {
+		err := GetEndClearLastTelegramError()
+
+		var panicErr *PanicError
+		assert.ErrorAs(t, err, &panicErr)
+		assert.Equal(t, "test panic", panicErr.Value)
+		assert.Contains(t, err.Error(), "TestTelegramController_RecoverPanic")
+		assert.Equal(t, panicCountBefore+1, PanicCount.Get())
+	}
+
+	t.Run("message", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.bot.Handle("/panic", func(c tele.Context) error {
+			panic("test panic")
+		})
+		panicCountBefore := PanicCount.Get()
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(expectedApologyMessage).
+			Reply(200).JSON(sendMessageSuccessResponse)
+
+		message := getTestSampleMessage()
+		message.Text = "/panic"
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+		assertPanicError(t, panicCountBefore)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		panicButton := &tele.InlineButton{Unique: "panic"}
+		telegramController.bot.Handle(panicButton, func(c tele.Context) error {
+			panic("test panic")
+		})
+		panicCountBefore := PanicCount.Get()
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(expectedApologyMessage).
+			Reply(200).JSON(sendMessageSuccessResponse)
+		NewGock().Times(1).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": "callback-123",
+			"text":              callbackErrorText,
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		message := getTestSampleMessage()
+		telegramController.bot.ProcessUpdate(tele.Update{
+			Callback: &tele.Callback{
+				ID:      "callback-123",
+				Message: &message,
+				Data:    "\f" + panicButton.Unique,
+				Sender:  message.Sender,
+			},
+		})
+
+		assertPanicError(t, panicCountBefore)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("throttle_reject_handler", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.senderThrottle.limit = rate.Every(time.Minute)
+		telegramController.senderThrottle.burst = 1
+		telegramController.bot.Handle("/ping", func(c tele.Context) error {
+			return nil
+		})
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		message := getTestSampleMessage()
+		message.Text = "/ping"
+		telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+		assert.NoError(t, GetEndClearLastTelegramError())
+
+		// the next update is rejected and ThrottledAction panics on sending with nil retry policy
+		telegramController.retryPolicy = nil
+		panicCountBefore := PanicCount.Get()
+
+		assert.NotPanics(t, func() {
+			telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+		})
+
+		var panicErr *PanicError
+		assert.ErrorAs(t, GetEndClearLastTelegramError(), &panicErr)
+		assert.Equal(t, panicCountBefore+1, PanicCount.Get())
+	})
+}
+
+func TestTelegramController_Throttle(t *testing.T) {
+	t.Run("rate", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.senderThrottle.limit = rate.Every(time.Minute)
+		telegramController.senderThrottle.burst = 1
+
+		handledCount := 0
+		telegramController.bot.Handle("/ping", func(c tele.Context) error {
+			handledCount++
+			return nil
+		})
+		throttledBefore := ThrottledRateTotal.Get()
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+			"chat_id":    testTelegramUserIdString,
+			"parse_mode": string(testPref.ParseMode),
+			"text":       throttledText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		for i := 0; i < 3; i++ {
+			message := getTestSampleMessage()
+			message.Text = "/ping"
+			telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+		}
+
+		assert.Equal(t, 1, handledCount)
+		assert.Equal(t, throttledBefore+2, ThrottledRateTotal.Get())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("duplicate_callback", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
 
-		telegramController.bot.ProcessUpdate(tele.Update{
-			Message: &message,
-			Callback: &tele.Callback{
-				Data:   button.Data,
-				Sender: message.Sender,
-			},
+		handledCount := 0
+		pingButton := &tele.InlineButton{Unique: "ping"}
+		telegramController.bot.Handle(pingButton, func(c tele.Context) error {
+			handledCount++
+			return nil
 		})
+		duplicateBefore := ThrottledDuplicateTotal.Get()
 
-		assert.Equal(t, expectedError, GetEndClearLastTelegramError())
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		defer gock.Off()
+		NewGock().Times(2).Post("/answerCallbackQuery").JSON(map[string]interface{}{
+			"callback_query_id": "callback-123",
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		for i := 0; i < 2; i++ {
+			message := getTestSampleMessage()
+			telegramController.bot.ProcessUpdate(tele.Update{
+				Callback: &tele.Callback{
+					ID:      "callback-123",
+					Message: &message,
+					Data:    "\f" + pingButton.Unique + "|1",
+					Sender:  message.Sender,
+				},
+			})
+		}
+
+		assert.Equal(t, 1, handledCount)
+		assert.Equal(t, duplicateBefore+1, ThrottledDuplicateTotal.Get())
 		assert.True(t, gock.IsDone())
-		assert.Contains(t, out.String(), `Failed to remove reply markup: telegram: message is not modified (400)`)
 	})
 }
 
@@ -1041,7 +2417,9 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 	previousScore := &scoreApi.Score{}
 
 	//
-	disciplineButton := telegramController.markups.disciplineButton.With(strconv.Itoa(disciplineScore.Discipline.Id))
+	disciplineButton := telegramController.callbackButton(
+		telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: disciplineScore.Discipline.Id, ViewMode: viewModeNotification},
+	)
 	disciplineButton.Text = disciplineScore.Discipline.Name
 
 	replyMarkup := &tele.ReplyMarkup{
@@ -1053,16 +2431,16 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 
 	expectedSendMessage := map[string]interface{}{
 		"chat_id":      testTelegramUserIdString,
-		"parse_mode":   "Markdown",
+		"parse_mode":   string(testPref.ParseMode),
 		"reply_markup": replyMarkupJson,
 		"text":         testMessageText,
 	}
 	//
 
 	messageData := models.ScoreChangedMessageData{
-		Discipline: disciplineScore.Discipline,
-		Score:      disciplineScore.Score,
-		Previous:   *previousScore,
+		Discipline: markDownDiscipline(disciplineScore.Discipline),
+		Score:      markDownScore(disciplineScore.Score),
+		Previous:   markDownScore(*previousScore),
 	}
 
 	t.Run("send_new_message", func(t *testing.T) {
@@ -1085,6 +2463,33 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 		assert.Equal(t, strconv.Itoa(testTelegramSendMessageId), actualMessageId)
 	})
 
+	t.Run("invalidate_score_cache", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+		scoreClient.On("GetStudentDisciplines", sampleStudent.Id).Return(scoreApi.DisciplineScoreResults{}, nil).Twice()
+		telegramController.scoreCache = NewScoreCache(scoreClient, time.Minute, time.Hour)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", messageData).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(200).JSON(sendMessageSuccessResponse)
+
+		_, _ = telegramController.scoreCache.GetStudentDisciplines(sampleStudent.Id)
+		_, _ = telegramController.scoreCache.GetStudentDisciplines(sampleStudent.Id)
+
+		actualErr, _ := telegramController.ScoreChangedAction(testTelegramUserIdString, "", disciplineScore, previousScore)
+		assert.NoError(t, actualErr)
+
+		_, _ = telegramController.scoreCache.GetStudentDisciplines(sampleStudent.Id)
+		scoreClient.AssertNumberOfCalls(t, "GetStudentDisciplines", 2)
+		assert.True(t, gock.IsDone())
+	})
+
 	t.Run("edit_previous_message", func(t *testing.T) {
 		var previousChatMessageIdInt = 6655443322
 		var previousChatMessageId = strconv.Itoa(previousChatMessageIdInt)
@@ -1092,7 +2497,7 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 		thisCaseExpectedMessageSend := map[string]interface{}{
 			"chat_id":      testTelegramUserIdString,
 			"message_id":   previousChatMessageId,
-			"parse_mode":   "Markdown",
+			"parse_mode":   string(testPref.ParseMode),
 			"reply_markup": replyMarkupJson,
 			"text":         testMessageText,
 		}
@@ -1124,6 +2529,55 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 			runEditScoreFlow(t)
 		})
 
+		t.Run("transient_error_retry", func(t *testing.T) {
+			telegramSuccessResponse := map[string]interface{}{
+				"ok": true,
+				"result": map[string]interface{}{
+					"message_id": previousChatMessageIdInt,
+				},
+			}
+
+			defer gock.Off()
+			NewGock().Times(1).Post("/editMessageText").JSON(thisCaseExpectedMessageSend).
+				Reply(502).JSON(map[string]interface{}{
+				"ok":          false,
+				"error_code":  502,
+				"description": "Bad Gateway",
+			})
+			NewGock().Times(1).Post("/editMessageText").JSON(thisCaseExpectedMessageSend).
+				Reply(200).JSON(telegramSuccessResponse)
+
+			runEditScoreFlow(t)
+		})
+
+		t.Run("parse_entities_fallback", func(t *testing.T) {
+			telegramSuccessResponse := map[string]interface{}{
+				"ok": true,
+				"result": map[string]interface{}{
+					"message_id": previousChatMessageIdInt,
+				},
+			}
+
+			plainTextMessageSend := map[string]interface{}{
+				"chat_id":      testTelegramUserIdString,
+				"message_id":   previousChatMessageId,
+				"reply_markup": replyMarkupJson,
+				"text":         testMessageText,
+			}
+
+			defer gock.Off()
+			NewGock().Times(1).Post("/editMessageText").JSON(thisCaseExpectedMessageSend).
+				Reply(400).JSON(map[string]interface{}{
+				"ok":          false,
+				"error_code":  400,
+				"description": "Bad Request: can't parse entities: Can't find end of Italic entity at byte offset 12",
+			})
+			NewGock().Times(1).Post("/editMessageText").JSON(plainTextMessageSend).
+				Reply(200).JSON(telegramSuccessResponse)
+
+			runEditScoreFlow(t)
+		})
+
 		t.Run("same_content_error", func(t *testing.T) {
 			sameContentError := map[string]interface{}{
 				"ok":          false,
@@ -1159,9 +2613,9 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 			}
 
 			thisCaseMessageData := models.ScoreChangedMessageData{
-				Discipline: disciplineScore.Discipline,
-				Score:      disciplineScore.Score,
-				Previous:   *thisCasePreviousScore,
+				Discipline: markDownDiscipline(disciplineScore.Discipline),
+				Score:      markDownScore(disciplineScore.Score),
+				Previous:   markDownScore(*thisCasePreviousScore),
 			}
 
 			messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
@@ -1187,9 +2641,9 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 			}
 
 			thisCaseMessageData := models.ScoreChangedMessageData{
-				Discipline: disciplineScore.Discipline,
-				Score:      disciplineScore.Score,
-				Previous:   *thisCasePreviousScore,
+				Discipline: markDownDiscipline(disciplineScore.Discipline),
+				Score:      markDownScore(disciplineScore.Score),
+				Previous:   markDownScore(*thisCasePreviousScore),
 			}
 
 			messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
@@ -1253,6 +2707,182 @@ func TestTelegramController_ScoreChangedAction(t *testing.T) {
 	})
 }
 
+func TestTelegramController_ScoreChangedActionOutbox(t *testing.T) {
+	entry := makeTestOutboxEntry(testTelegramUserIdString, 12, 150, 2.5)
+	entry.StudentId = sampleStudent.Id
+	previousScore := &scoreApi.Score{}
+
+	openOutbox := func(t *testing.T) *FileOutbox {
+		outbox, err := OpenFileOutbox(filepath.Join(t.TempDir(), "outbox.log"))
+		assert.NoError(t, err)
+		t.Cleanup(func() {
+			_ = outbox.Close()
+		})
+		return outbox
+	}
+
+	t.Run("ack_after_success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.outbox = openOutbox(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(200).JSON(sendMessageSuccessResponse)
+
+		actualErr, actualMessageId := telegramController.ScoreChangedAction(
+			testTelegramUserIdString, "", &entry.DisciplineScore, previousScore,
+		)
+
+		assert.NoError(t, actualErr)
+		assert.Equal(t, strconv.Itoa(testTelegramSendMessageId), actualMessageId)
+		assert.Empty(t, telegramController.outbox.Pending())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("keep_after_cancel", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.outbox = openOutbox(t)
+		telegramController.cancel()
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		actualErr, actualMessageId := telegramController.ScoreChangedAction(
+			testTelegramUserIdString, "", &entry.DisciplineScore, previousScore,
+		)
+
+		assert.ErrorIs(t, actualErr, ErrSendCancelled)
+		assert.Empty(t, actualMessageId)
+
+		pending := telegramController.outbox.Pending()
+		assert.Len(t, pending, 1)
+		assert.Equal(t, entry.Key(), pending[0].Key())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("ack_after_permanent_error", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.outbox = openOutbox(t)
+
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", mock.Anything).Return(nil, testMessageText)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(400).JSON(map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": "Bad Request: message text is empty",
+		})
+
+		actualErr, _ := telegramController.ScoreChangedAction(
+			testTelegramUserIdString, "", &entry.DisciplineScore, previousScore,
+		)
+
+		assert.Error(t, actualErr)
+		assert.Empty(t, telegramController.outbox.Pending())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("replay", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.outbox = openOutbox(t)
+		assert.NoError(t, telegramController.outbox.Put(entry))
+
+		messageIdStorage := mocks.NewScoreChangedMessageIdStorageInterface(t)
+		messageIdStorage.On(
+			"Set", uint(sampleStudent.Id), uint(150), testTelegramUserIdString, strconv.Itoa(testTelegramSendMessageId),
+		).Return().Once()
+		telegramController.scoreChangedMessageIdStorage = messageIdStorage
+
+		messageCompose := telegramController.composer.(*mocks.MessageComposerInterface)
+		messageCompose.On("ComposeScoreChanged", models.ScoreChangedMessageData{
+			Discipline: markDownDiscipline(entry.DisciplineScore.Discipline),
+			Score:      markDownScore(entry.DisciplineScore.Score),
+			Previous:   markDownScore(entry.PreviousScore),
+		}).Return(nil, testMessageText).Once()
+
+		replayedBefore := OutboxReplayedTotal.Get()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/sendMessage").Reply(200).JSON(sendMessageSuccessResponse)
+
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		telegramController.replayOutbox(wg)
+		wg.Wait()
+
+		assert.Equal(t, replayedBefore+1, OutboxReplayedTotal.Get())
+		assert.Empty(t, telegramController.outbox.Pending())
+		assert.True(t, gock.IsDone())
+
+		// the framework read the previous message id before replay saved it
+		userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+		userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+		messageCompose.On("ComposeScoreChanged", mock.Anything).Return(nil, testMessageText).Once()
+
+		disciplineButton := telegramController.callbackButton(
+			telegramController.markups.disciplineButton, CallbackPayload{DisciplineId: entry.DisciplineScore.Discipline.Id, ViewMode: viewModeNotification},
+		)
+		disciplineButton.Text = entry.DisciplineScore.Discipline.Name
+		replyMarkup := &tele.ReplyMarkup{
+			OneTimeKeyboard: true,
+			InlineKeyboard:  [][]tele.InlineButton{{*disciplineButton}},
+		}
+		ProcessReplyMarkup(replyMarkup)
+
+		NewGock().Times(1).Post("/editMessageText").JSON(map[string]interface{}{
+			"chat_id":      testTelegramUserIdString,
+			"message_id":   strconv.Itoa(testTelegramSendMessageId),
+			"parse_mode":   string(testPref.ParseMode),
+			"reply_markup": toJson(replyMarkup),
+			"text":         testMessageText,
+		}).Reply(200).JSON(sendMessageSuccessResponse)
+
+		actualErr, actualMessageId := telegramController.ScoreChangedAction(
+			testTelegramUserIdString, "", &entry.DisciplineScore, previousScore,
+		)
+
+		assert.NoError(t, actualErr)
+		assert.Equal(t, strconv.Itoa(testTelegramSendMessageId), actualMessageId)
+		assert.Empty(t, telegramController.outbox.Pending())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("replay_replaced_entry", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.outbox = openOutbox(t)
+		telegramController.scoreChangedMessageIdStorage = mocks.NewScoreChangedMessageIdStorageInterface(t)
+
+		replayed := makeTestOutboxEntry(testTelegramUserIdString, 12, 150, 2.5)
+		assert.NoError(t, telegramController.outbox.Put(replayed))
+		assert.NoError(t, telegramController.outbox.Put(makeTestOutboxEntry(testTelegramUserIdString, 12, 150, 4)))
+
+		replayedBefore := OutboxReplayedTotal.Get()
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		telegramController.replayOutboxEntry(replayed)
+
+		assert.Equal(t, replayedBefore, OutboxReplayedTotal.Get())
+		assert.Len(t, telegramController.outbox.Pending(), 1)
+	})
+}
+
 func floatPointer(value float32) *float32 {
 	return &value
 }
@@ -1262,6 +2892,13 @@ func toJson(v interface{}) string {
 	return string(jsonData)
 }
 
+func makeTestCallbackData(telegramController *TelegramController, button *tele.InlineButton, payload CallbackPayload) string {
+	callbackButton := telegramController.callbackButton(button, payload)
+	ProcessInlineButton(callbackButton)
+
+	return callbackButton.Data
+}
+
 func ProcessReplyMarkup(markup *tele.ReplyMarkup) {
 	result := tele.ResultBase{
 		ParseMode:   tele.ModeMarkdown,
@@ -1289,3 +2926,181 @@ func PrintBodyMatcher(req *http.Request, ereq *gock.Request) (bool, error) {
 
 	return true, nil
 }
+
+func TestTelegramController_Ready(t *testing.T) {
+	telegramController := CreateTelegramController(t)
+	telegramController.bot.Me.ID = 0
+
+	assert.True(t, errors.Is(telegramController.Ready(), ErrBotUserNotResolved))
+
+	telegramController.bot.Me.ID = 1
+	assert.True(t, errors.Is(telegramController.Ready(), ErrPollingNotStarted))
+
+	telegramController.poller = NewTrackedPoller(&blockingPoller{})
+	assert.True(t, errors.Is(telegramController.Ready(), ErrPollingNotStarted))
+
+	telegramController.poller.polling.Store(true)
+	assert.NoError(t, telegramController.Ready())
+
+	scoreClient := telegramController.scoreClient.(*scoreMocks.ClientInterface)
+	scoreClient.On("GetStudentDisciplines", uint32(999)).Return(nil, errors.New("expected error")).Once()
+
+	telegramController.scoreCircuitBreaker = NewScoreCircuitBreaker(scoreClient, 1, time.Millisecond*50)
+	_, err := telegramController.scoreCircuitBreaker.GetStudentDisciplines(999)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(telegramController.Ready(), ErrScoreCircuitOpen))
+
+	// webhook mode could receive no updates calling the score service, readiness is restored after open timeout
+	assert.Eventually(t, func() bool {
+		return telegramController.Ready() == nil
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestTelegramController_RegisterCommands(t *testing.T) {
+	scope := map[string]interface{}{"type": "all_private_chats"}
+
+	t.Run("success", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.bot.Me.ID = 1
+		defer func() { telegramController.bot.Me.ID = 0 }()
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/setMyCommands").JSON(map[string]interface{}{
+			"commands": []map[string]string{
+				{"command": "start", "description": "Почати роботу з ботом"},
+				{"command": "list", "description": "Мої результати"},
+				{"command": "reset", "description": "Вийти з облікового запису"},
+				{"command": "help", "description": "Довідка"},
+			},
+			"scope": scope,
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		NewGock().Times(1).Post("/setMyCommands").JSON(map[string]interface{}{
+			"commands": []map[string]string{
+				{"command": "start", "description": "Start the bot"},
+				{"command": "list", "description": "My scores"},
+				{"command": "reset", "description": "Log out"},
+				{"command": "help", "description": "Help"},
+			},
+			"scope":         scope,
+			"language_code": "en",
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		telegramController.registerCommands()
+
+		assert.True(t, gock.IsDone())
+		assert.Empty(t, telegramController.out.(*bytes.Buffer).String())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+		telegramController.bot.Me.ID = 1
+		defer func() { telegramController.bot.Me.ID = 0 }()
+		errorCountBefore := SetCommandsErrorCount.Get()
+
+		defer gock.Off()
+		NewGock().Times(2).Post("/setMyCommands").
+			Reply(400).JSON(map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": "Bad Request: command description is empty",
+		})
+
+		telegramController.registerCommands()
+
+		assert.True(t, gock.IsDone())
+		assert.Equal(t, errorCountBefore+2, SetCommandsErrorCount.Get())
+		assert.Contains(t, telegramController.out.(*bytes.Buffer).String(), `msg="Failed to set bot commands"`)
+	})
+
+	t.Run("offline", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		defer gock.Off()
+		NewGock().Times(0)
+
+		telegramController.registerCommands()
+
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("descriptions", func(t *testing.T) {
+		telegramController := CreateTelegramController(t)
+
+		for _, route := range telegramController.commandRoutes() {
+			assert.NotEmpty(t, route.description, route.command)
+			assert.NotEmpty(t, route.descriptionEn, route.command)
+			assert.NotNil(t, route.handler, route.command)
+		}
+	})
+}
+
+func TestTelegramController_HelpAction(t *testing.T) {
+	for _, parseMode := range testParseModes {
+		t.Run(string(parseMode), func(t *testing.T) {
+			t.Run("anonymous", func(t *testing.T) {
+				telegramController := CreateTelegramControllerWithParseMode(t, parseMode)
+
+				userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+				userRepository.On("GetStudent", testTelegramUserIdString).Return(nil).Once()
+
+				_, expectedText := telegramController.helpComposer.ComposeHelpAnonymousMessage(HelpMessageData{
+					SupportInfo: markDownData(SupportInfo),
+				})
+
+				defer gock.Off()
+				NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+					"chat_id":    testTelegramUserIdString,
+					"parse_mode": string(parseMode),
+					"text":       expectedText,
+				}).Reply(200).JSON(sendMessageSuccessResponse)
+
+				message := getTestSampleMessage()
+				message.Text = helpCommand
+
+				telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+				assert.True(t, gock.IsDone())
+				telegramController.authorizerClient.(*authorizerMocks.ClientInterface).AssertNotCalled(t, "GetAuthUrl", mock.Anything, mock.Anything)
+			})
+
+			t.Run("authorized", func(t *testing.T) {
+				telegramController := CreateTelegramControllerWithParseMode(t, parseMode)
+
+				userRepository := telegramController.userRepository.(*mocks.UserRepositoryInterface)
+				userRepository.On("GetStudent", testTelegramUserIdString).Return(sampleStudent).Once()
+
+				_, expectedText := telegramController.helpComposer.ComposeHelpAuthorizedMessage(HelpMessageData{
+					StudentMessageData: markDownStudentMessageData(sampleStudent),
+					SupportInfo:        markDownData(SupportInfo),
+				})
+
+				defer gock.Off()
+				NewGock().Times(1).Post("/sendMessage").JSON(map[string]interface{}{
+					"chat_id":    testTelegramUserIdString,
+					"parse_mode": string(parseMode),
+					"text":       expectedText,
+				}).Reply(200).JSON(sendMessageSuccessResponse)
+
+				message := getTestSampleMessage()
+				message.Text = helpCommand
+
+				telegramController.bot.ProcessUpdate(tele.Update{Message: &message})
+
+				assert.True(t, gock.IsDone())
+			})
+		})
+	}
+}
+
+func makeTestWelcomeReplyMarkup(telegramController *TelegramController, authUrl string) *tele.ReplyMarkup {
+	replyMarkup := &tele.ReplyMarkup{
+		InlineKeyboard: [][]tele.InlineButton{
+			{{Text: authUrlButtonText, URL: authUrl}},
+			{*telegramController.callbackButton(telegramController.markups.refreshAuthUrlButton, CallbackPayload{})},
+		},
+	}
+	ProcessReplyMarkup(replyMarkup)
+
+	return replyMarkup
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	tele "gopkg.in/telebot.v3"
-	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+var ErrSendCancelled = errors.New("send cancelled")
+
 func makeChatId(chatId string) tele.ChatID {
 	chatIdInt, _ := strconv.ParseInt(chatId, 10, 0)
 	return tele.ChatID(chatIdInt)
@@ -18,23 +23,76 @@ func makeInt64(input string) int64 {
 	return output
 }
 
-/** @see https://regex101.com/r/5zFBzu/1 */
-var unEscapeMarkDownLinks = regexp.MustCompile(`(?m)\\\[([^\[\]]*)\\\]\\\(([^\)]*)\\\)`)
-var unEscapeMarkDownLinksSubstitution = "[$1]($2)"
+var markDownFormatChars = "*_~|`"
+
+// stripMarkDown converts MarkdownV2 text to plain text: formatting chars are dropped,
+// escaped chars are unescaped and inline links are rendered as "text (url)".
+func stripMarkDown(markdownStr string) string {
+	chars := []rune(markdownStr)
+	output := strings.Builder{}
+	inLinkUrl := false
+
+	for i := 0; i < len(chars); i++ {
+		char := chars[i]
+		switch {
+		case char == '\\' && i+1 < len(chars):
+			i++
+			output.WriteRune(chars[i])
+
+		case inLinkUrl && char == ')':
+			inLinkUrl = false
+			output.WriteRune(char)
+
+		case inLinkUrl:
+			output.WriteRune(char)
+
+		case char == '[':
+			// opening bracket of inline link
+
+		case char == ']' && i+1 < len(chars) && chars[i+1] == '(':
+			inLinkUrl = true
+			i++
+			output.WriteString(" (")
+
+		case !strings.ContainsRune(markDownFormatChars, char):
+			output.WriteRune(char)
+		}
+	}
+
+	return output.String()
+}
+
+func isShortDisciplineName(name string) bool {
+	return utf8.RuneCountInString(name) <= shortDisciplineNameLength
+}
 
-func escapeMarkDown(markdownStr string) string {
-	// do not escape special chars to keep format: * bold; _ italic; ~strikethrough; | - spoiler;
-	escapeChar := []string{"[", "]", "(", ")", ">", "#", "+", "-", "=", "{", "}", ".", "!", "`"}
-	for _, char := range escapeChar {
-		if strings.Contains(markdownStr, char) {
-			markdownStr = strings.ReplaceAll(markdownStr, char, "\\"+char)
+func withoutReplyMarkup(opts []interface{}) []interface{} {
+	output := make([]interface{}, 0, len(opts))
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case *tele.ReplyMarkup:
+			continue
+		case *tele.SendOptions:
+			if opt != nil && opt.ReplyMarkup != nil {
+				sendOptions := *opt
+				sendOptions.ReplyMarkup = nil
+				output = append(output, &sendOptions)
+				continue
+			}
 		}
+		output = append(output, opt)
 	}
 
-	// drop escaping from inline links
-	markdownStr = unEscapeMarkDownLinks.ReplaceAllString(markdownStr, unEscapeMarkDownLinksSubstitution)
+	return output
+}
+
+func isParseEntitiesErr(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "can't parse entities")
+}
 
-	return markdownStr
+func isMessageNotEditableErr(err error) bool {
+	return errors.Is(err, tele.ErrCantEditMessage) ||
+		(err != nil && strings.Contains(err.Error(), "message to edit not found"))
 }
 
 func isBlockedByUserErr(err error) bool {
@@ -47,3 +105,19 @@ func isBlockedByUserErr(err error) bool {
 	}
 	return false
 }
+
+func newSendCancelledError(ctx context.Context) error {
+	return fmt.Errorf("%w: %w", ErrSendCancelled, ctx.Err())
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return newSendCancelledError(ctx)
+	}
+}
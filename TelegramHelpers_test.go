@@ -1,39 +1,60 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
-func Test_EscapeMarkDown(t *testing.T) {
-	t.Run("usual", func(t *testing.T) {
-		input := "Задачі ~прикладного~ системного аналізу: 50\nрейтинг #1/20."
-		expected := "Задачі ~прикладного~ системного аналізу: 50\nрейтинг \\#1/20\\."
+func Test_SleepContext(t *testing.T) {
+	t.Run("elapsed", func(t *testing.T) {
+		start := time.Now()
+		err := sleepContext(context.Background(), time.Millisecond*50)
 
-		assert.Equal(t, expected, escapeMarkDown(input))
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*50)
 	})
 
-	t.Run("brackets-with-leading-space", func(t *testing.T) {
-		input := "Бали: *3* _(було 1)_"
-		expected := "Бали: *3* _\\(було 1\\)_"
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
 
-		assert.Equal(t, expected, escapeMarkDown(input))
+		start := time.Now()
+		err := sleepContext(ctx, time.Minute)
+
+		assert.ErrorIs(t, err, ErrSendCancelled)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func Test_StripMarkDown(t *testing.T) {
+	t.Run("format", func(t *testing.T) {
+		input := "Бали: *3* _\\(було 1\\)_ ~2~ ||спойлер|| `код` рейтинг \\#1/20\\."
+		expected := "Бали: 3 (було 1) 2 спойлер код рейтинг #1/20."
+
+		assert.Equal(t, expected, stripMarkDown(input))
 	})
 
-	t.Run("brackets-without-leading-space", func(t *testing.T) {
-		input := "Бали: 3(було 1)"
-		expected := "Бали: 3\\(було 1\\)"
+	t.Run("escaped-format-chars", func(t *testing.T) {
+		input := "Капітал\\_2\\* \\[3 та 3\\]"
+		expected := "Капітал_2* [3 та 3]"
 
-		assert.Equal(t, expected, escapeMarkDown(input))
+		assert.Equal(t, expected, stripMarkDown(input))
 	})
 
 	t.Run("links", func(t *testing.T) {
-		input := "Бали: 3 (було 1) ото таке [3 та 3].\n" +
-			"Перевіряйте оцінки в [офіційному журналі успішності КНЕУ](https://cutt.ly/Dekanat)"
-		expected := "Бали: 3 \\(було 1\\) ото таке \\[3 та 3\\]\\.\n" +
-			"Перевіряйте оцінки в [офіційному журналі успішності КНЕУ](https://cutt\\.ly/Dekanat)"
+		input := "Перевіряйте оцінки в [офіційному *журналі*](https://cutt\\.ly/Dekanat_1)\\."
+		expected := "Перевіряйте оцінки в офіційному журналі (https://cutt.ly/Dekanat_1)."
 
-		assert.Equal(t, expected, escapeMarkDown(input))
+		assert.Equal(t, expected, stripMarkDown(input))
 	})
+}
 
+func Test_IsParseEntitiesErr(t *testing.T) {
+	assert.True(t, isParseEntitiesErr(errors.New("telegram: Bad Request: can't parse entities: Can't find end of Underline entity (400)")))
+	assert.False(t, isParseEntitiesErr(errors.New("telegram: Bad Request: chat not found (400)")))
+	assert.False(t, isParseEntitiesErr(nil))
 }
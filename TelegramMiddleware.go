@@ -1,14 +1,31 @@
 package main
 
 import (
+	"fmt"
 	framework "github.com/kneu-messenger-pigeon/client-framework"
 	"github.com/kneu-messenger-pigeon/client-framework/models"
 	tele "gopkg.in/telebot.v3"
+	"runtime/debug"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const contextStudentKey = "student"
 
+const contextCallbackResponseKey = "callbackResponse"
+
+// PanicError is returned by recoverMiddleware instead of panic of the handler.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (err *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", err.Value, err.Stack)
+}
+
 func getStudent(c tele.Context) *models.Student {
 	student := c.Get(contextStudentKey)
 	if student == nil {
@@ -17,6 +34,95 @@ func getStudent(c tele.Context) *models.Student {
 	return student.(*models.Student)
 }
 
+// setCallbackResponse sets toast or alert text for the answer of callback query sent by respondCallbackMiddleware.
+func setCallbackResponse(c tele.Context, text string, showAlert bool) {
+	c.Set(contextCallbackResponseKey, &tele.CallbackResponse{
+		Text:      text,
+		ShowAlert: showAlert,
+	})
+}
+
+// respondCallbackMiddleware answers every callback query, so Telegram clients stop showing loader on the button.
+// Failed handlers without own response show generic error toast.
+func respondCallbackMiddleware(debugLogger *framework.DebugLogger) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			err := next(c)
+			if c.Callback() == nil {
+				return err
+			}
+
+			response, _ := c.Get(contextCallbackResponseKey).(*tele.CallbackResponse)
+			if response == nil && err != nil {
+				response = &tele.CallbackResponse{Text: callbackErrorText}
+			}
+
+			var respondErr error
+			if response != nil {
+				respondErr = c.Respond(response)
+			} else {
+				respondErr = c.Respond()
+			}
+			if respondErr != nil {
+				debugLogger.Log("Failed to answer callback query: %v", respondErr)
+			}
+
+			return err
+		}
+	}
+}
+
+// recoverMiddleware converts panic of the next handlers to PanicError, so it is reported by bot OnError
+// with the stack trace, and calls panicHandler to notify the user.
+func recoverMiddleware(panicHandler tele.HandlerFunc) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) (err error) {
+			defer func() {
+				if value := recover(); value != nil {
+					PanicCount.Inc()
+					err = &PanicError{Value: value, Stack: debug.Stack()}
+					_ = panicHandler(c)
+				}
+			}()
+
+			return next(c)
+		}
+	}
+}
+
+// throttleMiddleware drops excess updates of private chat senders and repeated taps of the same button.
+// rejectHandler is called instead of the next handler to notify the sender, see SenderThrottle.Check.
+func throttleMiddleware(throttle *SenderThrottle, rejectHandler tele.HandlerFunc) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if c.Sender() == nil || c.Chat() == nil || c.Chat().Type != tele.ChatPrivate {
+				return next(c)
+			}
+
+			callback := ""
+			if c.Callback() != nil {
+				callback = c.Callback().Unique + "|" + c.Callback().Data
+			}
+
+			switch throttle.Check(c.Sender().ID, callback, time.Now()) {
+			case throttleDuplicate:
+				ThrottledDuplicateTotal.Inc()
+				return nil
+
+			case throttleReject:
+				ThrottledRateTotal.Inc()
+				return rejectHandler(c)
+
+			case throttleDrop:
+				ThrottledRateTotal.Inc()
+				return nil
+			}
+
+			return next(c)
+		}
+	}
+}
+
 func authMiddleware(userRepository framework.UserRepositoryInterface) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
@@ -30,13 +136,20 @@ func authMiddleware(userRepository framework.UserRepositoryInterface) tele.Middl
 	}
 }
 
-func onlyAuthorizedMiddleware(anonymousHandler tele.HandlerFunc) tele.MiddlewareFunc {
+// onlyAuthorizedMiddleware passes updates of anonymous users to anonymousHandler, except of publicCommands.
+func onlyAuthorizedMiddleware(anonymousHandler tele.HandlerFunc, publicCommands ...string) tele.MiddlewareFunc {
 	return func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
 			if getStudent(c) != nil {
 				return next(c)
 			}
 
+			// command could be addressed to the bot as /help@bot
+			command, _, _ := strings.Cut(updateAction(c), "@")
+			if slices.Contains(publicCommands, command) {
+				return next(c)
+			}
+
 			return anonymousHandler(c)
 		}
 	}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	tele "gopkg.in/telebot.v3"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const webhookSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+const webhookShutdownTimeout = time.Second * 5
+
+var webhookAllowedUpdates = []string{"message", "callback_query"}
+
+// WebhookPoller is an alternative to tele.LongPoller: Telegram pushes updates to the public URL,
+// and they are fed into the same bot routes as polled ones.
+// Failures to set the webhook or to listen are retried with backoff until the bot is stopped.
+type WebhookPoller struct {
+	listen      string
+	publicURL   string
+	secretToken string
+	retryPolicy *RetryPolicy
+	// removeOnStop deletes the webhook on stop. It is off by default, because replicas share the bot and public URL,
+	// so the stopped replica would remove the webhook set by the running ones during rolling deploy.
+	removeOnStop bool
+
+	updates chan<- tele.Update
+	// running is set while the webhook is set and the server listens
+	running atomic.Bool
+}
+
+func NewWebhookPoller(listen string, publicURL string, secretToken string) *WebhookPoller {
+	return &WebhookPoller{
+		listen:      listen,
+		publicURL:   publicURL,
+		secretToken: secretToken,
+		retryPolicy: &RetryPolicy{
+			baseDelay: time.Second,
+			maxDelay:  time.Minute,
+		},
+	}
+}
+
+func makePoller(config Config) tele.Poller {
+	if config.telegramWebhookURL != "" {
+		poller := NewWebhookPoller(config.telegramWebhookListen, config.telegramWebhookURL, config.telegramWebhookSecret)
+		poller.removeOnStop = config.telegramWebhookRemoveOnStop
+		return poller
+	}
+
+	return &tele.LongPoller{
+		Timeout: time.Second * 30,
+	}
+}
+
+// Running reports whether Telegram could deliver updates to the poller.
+func (poller *WebhookPoller) Running() bool {
+	return poller.running.Load()
+}
+
+func (poller *WebhookPoller) Poll(bot *tele.Bot, updates chan tele.Update, stop chan struct{}) {
+	poller.updates = updates
+
+	for attempt := 0; ; attempt++ {
+		err := poller.serve(bot, stop)
+		if err == nil {
+			return
+		}
+
+		WebhookErrorCount.Inc()
+		bot.OnError(err, nil)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(poller.retryPolicy.Backoff(attempt)):
+		}
+	}
+}
+
+// serve sets the webhook and serves updates until stop. The webhook is kept on stop unless removeOnStop is set,
+// so Telegram keeps pending updates for the other replicas or until the server is restarted.
+func (poller *WebhookPoller) serve(bot *tele.Bot, stop chan struct{}) error {
+	err := bot.SetWebhook(&tele.Webhook{
+		AllowedUpdates: webhookAllowedUpdates,
+		SecretToken:    poller.secretToken,
+		Endpoint: &tele.WebhookEndpoint{
+			PublicURL: poller.publicURL,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", poller.listen)
+	if err != nil {
+		return fmt.Errorf("webhook server error: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           poller,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(listener)
+	}()
+
+	poller.running.Store(true)
+	defer poller.running.Store(false)
+
+	select {
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		err = server.Shutdown(ctx)
+		cancel()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			bot.OnError(fmt.Errorf("webhook server error: %w", err), nil)
+		}
+
+		if poller.removeOnStop {
+			err = bot.RemoveWebhook()
+			if err != nil {
+				bot.OnError(fmt.Errorf("failed to remove webhook: %w", err), nil)
+			}
+		}
+		return nil
+
+	case err = <-serverErr:
+		return fmt.Errorf("webhook server error: %w", err)
+	}
+}
+
+func (poller *WebhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	secretToken := r.Header.Get(webhookSecretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(secretToken), []byte(poller.secretToken)) != 1 {
+		WebhookUnauthorizedErrorCount.Inc()
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var update tele.Update
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		WebhookBadRequestErrorCount.Inc()
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case poller.updates <- update:
+		WebhookUpdatesTotal.Inc()
+		w.WriteHeader(http.StatusOK)
+
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
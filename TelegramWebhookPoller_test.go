@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testWebhookURL = "https://bot.kneu.test/webhook"
+const testWebhookSecret = "test-webhook-secret"
+
+func getFreeListenAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func makeWebhookRequest(url string, secretToken string, update tele.Update) *http.Request {
+	body, _ := json.Marshal(update)
+	request := httptest.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if secretToken != "" {
+		request.Header.Set(webhookSecretTokenHeader, secretToken)
+	}
+
+	return request
+}
+
+func TestWebhookPoller_ServeHTTP(t *testing.T) {
+	message := getTestSampleMessage()
+	message.Text = listCommand
+	update := tele.Update{ID: 987, Message: &message}
+
+	t.Run("success", func(t *testing.T) {
+		updates := make(chan tele.Update, 1)
+		poller := NewWebhookPoller(":0", testWebhookURL, testWebhookSecret)
+		poller.updates = updates
+
+		recorder := httptest.NewRecorder()
+		poller.ServeHTTP(recorder, makeWebhookRequest("/", testWebhookSecret, update))
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Len(t, updates, 1)
+
+		actualUpdate := <-updates
+		assert.Equal(t, update.ID, actualUpdate.ID)
+		assert.Equal(t, listCommand, actualUpdate.Message.Text)
+	})
+
+	t.Run("wrong_secret", func(t *testing.T) {
+		updates := make(chan tele.Update, 1)
+		poller := NewWebhookPoller(":0", testWebhookURL, testWebhookSecret)
+		poller.updates = updates
+
+		for _, secretToken := range []string{"", "wrong-secret"} {
+			recorder := httptest.NewRecorder()
+			poller.ServeHTTP(recorder, makeWebhookRequest("/", secretToken, update))
+
+			assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+			assert.Empty(t, updates)
+		}
+	})
+
+	t.Run("wrong_method", func(t *testing.T) {
+		poller := NewWebhookPoller(":0", testWebhookURL, testWebhookSecret)
+
+		recorder := httptest.NewRecorder()
+		poller.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+
+	t.Run("bad_body", func(t *testing.T) {
+		updates := make(chan tele.Update, 1)
+		poller := NewWebhookPoller(":0", testWebhookURL, testWebhookSecret)
+		poller.updates = updates
+
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString("{not-json"))
+		request.Header.Set(webhookSecretTokenHeader, testWebhookSecret)
+
+		recorder := httptest.NewRecorder()
+		poller.ServeHTTP(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Empty(t, updates)
+	})
+}
+
+func TestWebhookPoller_Poll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		listen := getFreeListenAddress(t)
+		poller := NewWebhookPoller(listen, testWebhookURL, testWebhookSecret)
+		poller.removeOnStop = true
+
+		pref := testPref
+		pref.Poller = poller
+		pref.OnError = func(err error, c tele.Context) {
+			assert.NoError(t, err)
+		}
+		bot, _ := tele.NewBot(pref)
+
+		handled := make(chan string, 1)
+		bot.Handle(listCommand, func(c tele.Context) error {
+			handled <- c.Text()
+			return nil
+		})
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/setWebhook").JSON(map[string]interface{}{
+			"allowed_updates": `["message","callback_query"]`,
+			"secret_token":    testWebhookSecret,
+			"url":             testWebhookURL,
+		}).Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		NewGock().Times(1).Post("/deleteWebhook").
+			Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		go bot.Start()
+
+		// gock replaces http.DefaultTransport, so local requests go through a dedicated transport
+		client := &http.Client{Transport: &http.Transport{}, Timeout: time.Second}
+
+		message := getTestSampleMessage()
+		message.Text = listCommand
+
+		var response *http.Response
+		var err error
+		for deadline := time.Now().Add(time.Second * 2); time.Now().Before(deadline); time.Sleep(time.Millisecond * 20) {
+			request := makeWebhookRequest("http://"+listen+"/", testWebhookSecret, tele.Update{ID: 1, Message: &message})
+			request.RequestURI = ""
+
+			response, err = client.Do(request)
+			if err == nil {
+				break
+			}
+		}
+
+		assert.NoError(t, err)
+		if response != nil {
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+			_ = response.Body.Close()
+		}
+
+		select {
+		case text := <-handled:
+			assert.Equal(t, listCommand, text)
+		case <-time.After(time.Second):
+			assert.Fail(t, "update was not handled")
+		}
+
+		bot.Stop()
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("setWebhookError", func(t *testing.T) {
+		poller := NewWebhookPoller(getFreeListenAddress(t), testWebhookURL, testWebhookSecret)
+		poller.retryPolicy = &RetryPolicy{baseDelay: time.Millisecond * 10, maxDelay: time.Millisecond * 50}
+		errorCountBefore := WebhookErrorCount.Get()
+
+		actualErr := make(chan error, 10)
+		pref := testPref
+		pref.Poller = poller
+		pref.OnError = func(err error, c tele.Context) {
+			actualErr <- err
+		}
+		bot, _ := tele.NewBot(pref)
+
+		defer gock.Off()
+		NewGock().Times(1).Post("/setWebhook").
+			Reply(400).JSON(map[string]interface{}{
+			"ok":          false,
+			"error_code":  400,
+			"description": "Bad Request: bad webhook: HTTPS url must be provided for webhook",
+		})
+		NewGock().Times(1).Post("/setWebhook").
+			Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			poller.Poll(bot, bot.Updates, stop)
+			close(done)
+		}()
+
+		assert.Eventually(t, poller.Running, time.Second, time.Millisecond*10)
+		close(stop)
+		<-done
+
+		assert.False(t, poller.Running())
+		assert.Len(t, actualErr, 1)
+		assert.ErrorContains(t, <-actualErr, "failed to set webhook")
+		assert.Equal(t, errorCountBefore+1, WebhookErrorCount.Get())
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("listenError", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+
+		poller := NewWebhookPoller(listener.Addr().String(), testWebhookURL, testWebhookSecret)
+		poller.retryPolicy = &RetryPolicy{baseDelay: time.Millisecond * 10, maxDelay: time.Millisecond * 50}
+
+		actualErr := make(chan error, 100)
+		pref := testPref
+		pref.Poller = poller
+		pref.OnError = func(err error, c tele.Context) {
+			actualErr <- err
+		}
+		bot, _ := tele.NewBot(pref)
+
+		defer gock.Off()
+		NewGock().Persist().Post("/setWebhook").
+			Reply(200).JSON(map[string]interface{}{"ok": true, "result": true})
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			poller.Poll(bot, bot.Updates, stop)
+			close(done)
+		}()
+
+		// the port is busy, so the poller keeps retrying without receiving updates
+		assert.ErrorContains(t, <-actualErr, "webhook server error")
+		assert.False(t, poller.Running())
+
+		_ = listener.Close()
+		assert.Eventually(t, poller.Running, time.Second, time.Millisecond*10)
+
+		close(stop)
+		<-done
+		assert.False(t, poller.Running())
+	})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	"github.com/kneu-messenger-pigeon/score-client"
+	"time"
+)
+
+// the score client uses http.DefaultClient without timeout, it should be shorter than handler timeout
+const defaultScoreClientTimeout = time.Second * 10
+
+var ErrScoreClientTimeout = errors.New("score service request timed out")
+
+// TimeoutScoreClient is a score.ClientInterface decorator limiting how long the caller waits for the score service.
+// The score client does not accept context, so the timed out request is left to finish in background,
+// while the timeout error is counted by the circuit breaker which stops new requests to the hung service.
+type TimeoutScoreClient struct {
+	client  score.ClientInterface
+	timeout time.Duration
+}
+
+func NewTimeoutScoreClient(client score.ClientInterface, timeout time.Duration) *TimeoutScoreClient {
+	return &TimeoutScoreClient{
+		client:  client,
+		timeout: timeout,
+	}
+}
+
+func (client *TimeoutScoreClient) GetStudentDisciplines(studentId uint32) (scoreApi.DisciplineScoreResults, error) {
+	return callWithTimeout(client.timeout, func() (scoreApi.DisciplineScoreResults, error) {
+		return client.client.GetStudentDisciplines(studentId)
+	})
+}
+
+func (client *TimeoutScoreClient) GetStudentDiscipline(studentId uint32, disciplineId int) (scoreApi.DisciplineScoreResult, error) {
+	return callWithTimeout(client.timeout, func() (scoreApi.DisciplineScoreResult, error) {
+		return client.client.GetStudentDiscipline(studentId, disciplineId)
+	})
+}
+
+func (client *TimeoutScoreClient) GetStudentScore(studentId uint32, disciplineId int, lessonId int) (scoreApi.DisciplineScore, error) {
+	return callWithTimeout(client.timeout, func() (scoreApi.DisciplineScore, error) {
+		return client.client.GetStudentScore(studentId, disciplineId, lessonId)
+	})
+}
+
+type timeoutCallResult[T any] struct {
+	response T
+	err      error
+}
+
+func callWithTimeout[T any](timeout time.Duration, call func() (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call()
+	}
+
+	// buffered, so the background request does not block after the timeout
+	done := make(chan timeoutCallResult[T], 1)
+	go func() {
+		response, err := call()
+		done <- timeoutCallResult[T]{response: response, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result.response, result.err
+
+	case <-timer.C:
+		ScoreClientTimeoutCount.Inc()
+		var response T
+		return response, ErrScoreClientTimeout
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	scoreApi "github.com/kneu-messenger-pigeon/score-api"
+	scoreMocks "github.com/kneu-messenger-pigeon/score-client/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestTimeoutScoreClient(t *testing.T) {
+	studentId := uint32(999)
+	disciplineId := 199
+	discipline := scoreApi.DisciplineScoreResult{Discipline: scoreApi.Discipline{Id: disciplineId}}
+
+	t.Run("in_time", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+		score := scoreApi.DisciplineScore{Discipline: discipline.Discipline}
+
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).Return(discipline, nil).Once()
+		scoreClient.On("GetStudentDisciplines", studentId).Return(nil, expectedError).Once()
+		scoreClient.On("GetStudentScore", studentId, disciplineId, 15).Return(score, nil).Once()
+
+		client := NewTimeoutScoreClient(scoreClient, time.Second)
+
+		actual, err := client.GetStudentDiscipline(studentId, disciplineId)
+		assert.NoError(t, err)
+		assert.Equal(t, discipline, actual)
+
+		_, err = client.GetStudentDisciplines(studentId)
+		assert.Equal(t, expectedError, err)
+
+		actualScore, err := client.GetStudentScore(studentId, disciplineId, 15)
+		assert.NoError(t, err)
+		assert.Equal(t, score, actualScore)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan time.Time)
+		defer close(release)
+
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDiscipline", studentId, disciplineId).WaitUntil(release).Return(discipline, nil).Once()
+
+		client := NewTimeoutScoreClient(scoreClient, time.Millisecond*20)
+		timeoutCountBefore := ScoreClientTimeoutCount.Get()
+
+		actual, err := client.GetStudentDiscipline(studentId, disciplineId)
+		assert.ErrorIs(t, err, ErrScoreClientTimeout)
+		assert.Empty(t, actual)
+		assert.Equal(t, timeoutCountBefore+1, ScoreClientTimeoutCount.Get())
+	})
+
+	t.Run("opens_circuit_breaker", func(t *testing.T) {
+		release := make(chan time.Time)
+		defer close(release)
+
+		scoreClient := scoreMocks.NewClientInterface(t)
+		scoreClient.On("GetStudentDisciplines", mock.Anything).WaitUntil(release).Return(nil, nil).Twice()
+
+		breaker := NewScoreCircuitBreaker(NewTimeoutScoreClient(scoreClient, time.Millisecond*20), 2, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			_, err := breaker.GetStudentDisciplines(studentId)
+			assert.ErrorIs(t, err, ErrScoreClientTimeout)
+		}
+
+		_, err := breaker.GetStudentDisciplines(studentId)
+		assert.ErrorIs(t, err, ErrScoreCircuitOpen)
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	tele "gopkg.in/telebot.v3"
+	"sync/atomic"
+)
+
+// runningPoller is implemented by pollers which know whether Telegram could deliver updates, like WebhookPoller.
+type runningPoller interface {
+	Running() bool
+}
+
+// TrackedPoller is a tele.Poller decorator reporting whether the bot receives updates, see TelegramController.Ready.
+type TrackedPoller struct {
+	poller  tele.Poller
+	polling atomic.Bool
+}
+
+func NewTrackedPoller(poller tele.Poller) *TrackedPoller {
+	return &TrackedPoller{
+		poller: poller,
+	}
+}
+
+func (tracked *TrackedPoller) Poll(bot *tele.Bot, updates chan tele.Update, stop chan struct{}) {
+	tracked.polling.Store(true)
+	defer tracked.polling.Store(false)
+
+	tracked.poller.Poll(bot, updates, stop)
+}
+
+// Running reports whether Poll is running and, when the poller knows it, whether updates could be delivered.
+func (tracked *TrackedPoller) Running() bool {
+	if !tracked.polling.Load() {
+		return false
+	}
+
+	if poller, isRunningPoller := tracked.poller.(runningPoller); isRunningPoller {
+		return poller.Running()
+	}
+
+	return true
+}
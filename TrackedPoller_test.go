@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
+	"testing"
+	"time"
+)
+
+// blockingPoller receives no updates until it is stopped
+type blockingPoller struct{}
+
+func (poller *blockingPoller) Poll(bot *tele.Bot, updates chan tele.Update, stop chan struct{}) {
+	<-stop
+}
+
+func TestTrackedPoller(t *testing.T) {
+	t.Run("poll", func(t *testing.T) {
+		tracked := NewTrackedPoller(&blockingPoller{})
+		assert.False(t, tracked.Running())
+
+		stop := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			tracked.Poll(nil, make(chan tele.Update), stop)
+			close(done)
+		}()
+
+		assert.Eventually(t, tracked.Running, time.Second, time.Millisecond*10)
+
+		close(stop)
+		<-done
+		assert.False(t, tracked.Running())
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		webhookPoller := NewWebhookPoller(":0", "https://example.com/webhook", "secret")
+		tracked := NewTrackedPoller(webhookPoller)
+		tracked.polling.Store(true)
+
+		assert.False(t, tracked.Running())
+
+		webhookPoller.running.Store(true)
+		assert.True(t, tracked.Running())
+	})
+}
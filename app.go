@@ -5,9 +5,8 @@ import (
 	framework "github.com/kneu-messenger-pigeon/client-framework"
 	tele "gopkg.in/telebot.v3"
 	"io"
-	"log"
+	"log/slog"
 	"os"
-	"time"
 )
 
 const ExitCodeMainError = 1
@@ -23,16 +22,15 @@ func runApp(out io.Writer) error {
 	}
 
 	config, err := loadConfig(envFilename)
+	logger := newLogger(out, config.logFormat)
 
 	pref := tele.Settings{
-		Token:   config.telegramToken,
-		Offline: config.telegramOffline,
-		URL:     config.telegramURL,
-		Poller: &tele.LongPoller{
-			Timeout: time.Second * 30,
-		},
-		ParseMode: tele.ModeMarkdownV2,
-		OnError:   TelegramOnError,
+		Token:     config.telegramToken,
+		Offline:   config.telegramOffline,
+		URL:       config.telegramURL,
+		Poller:    makePoller(config),
+		ParseMode: config.parseMode,
+		OnError:   newTelegramOnError(logger),
 	}
 
 	if err == nil {
@@ -44,12 +42,26 @@ func runApp(out io.Writer) error {
 	}
 
 	serviceContainer := framework.NewServiceContainer(config.BaseConfig, out)
-	telegramController := NewTelegramController(serviceContainer, bot, out)
+	telegramController, err := NewTelegramController(serviceContainer, bot, config, logger, out)
+	if err != nil {
+		return err
+	}
 	serviceContainer.SetController(telegramController)
 
 	serviceContainer.Executor.Execute()
 
-	return nil
+	if telegramController.outbox != nil {
+		err = telegramController.outbox.Close()
+	}
+
+	if telegramController.redis != nil {
+		closeErr := telegramController.redis.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
 }
 
 func handleExitError(errStream io.Writer, err error) int {
@@ -63,17 +75,14 @@ func handleExitError(errStream io.Writer, err error) int {
 	return 0
 }
 
-func TelegramOnError(err error, c tele.Context) {
-	if c != nil {
-		studentId := uint32(0)
-		if c.Get(contextStudentKey) != nil {
-			studentId = getStudent(c).Id
+func newTelegramOnError(logger *slog.Logger) func(err error, c tele.Context) {
+	return func(err error, c tele.Context) {
+		if c != nil {
+			logger.With(errorLogAttrs(err)...).Error("Failed to process update", updateLogAttrs(c)...)
+			OnUpdateErrorCount.Inc()
+		} else {
+			logger.With(errorLogAttrs(err)...).Error("Telegram bot error")
+			OnErrorCount.Inc()
 		}
-
-		log.Println(studentId, c.Update().ID, err)
-		OnUpdateErrorCount.Inc()
-	} else {
-		log.Println(err)
-		OnErrorCount.Inc()
 	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	framework "github.com/kneu-messenger-pigeon/client-framework"
@@ -140,9 +141,11 @@ func TestHandleExitError(t *testing.T) {
 
 func TestTelegramOnError(t *testing.T) {
 	t.Run("noContext", func(t *testing.T) {
-		TelegramOnError(errors.New("dummy error"), nil)
+		out := &bytes.Buffer{}
+		newTelegramOnError(newLogger(out, logFormatText))(errors.New("dummy error"), nil)
 
 		assert.Equal(t, uint64(1), OnErrorCount.Get())
+		assert.Contains(t, out.String(), `error="dummy error"`)
 	})
 
 	t.Run("Context", func(t *testing.T) {
@@ -150,13 +153,41 @@ func TestTelegramOnError(t *testing.T) {
 
 		ctx := bot.NewContext(tele.Update{
 			ID: 123,
+			Message: &tele.Message{
+				Text: "/list Мої результати",
+				Chat: &tele.Chat{ID: 456},
+			},
 		})
 		ctx.Set(contextStudentKey, &models.Student{
 			Id: 1,
 		})
+		ctx.Set(contextUpdateStartKey, time.Now())
 
-		TelegramOnError(errors.New("dummy error"), ctx)
+		out := &bytes.Buffer{}
+		newTelegramOnError(newLogger(out, logFormatJson))(errors.New("telegram: Bad Request (400)"), ctx)
 
 		assert.Equal(t, uint64(1), OnUpdateErrorCount.Get())
+
+		record := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "telegram: Bad Request (400)", record["error"])
+		assert.Equal(t, float64(400), record["telegram_error_code"])
+		assert.Equal(t, float64(123), record["update_id"])
+		assert.Equal(t, float64(456), record["chat_id"])
+		assert.Equal(t, float64(1), record["student_id"])
+		assert.Equal(t, "/list", record["action"])
+		assert.Contains(t, record, "duration")
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		err := &PanicError{Value: "test panic", Stack: []byte("goroutine 1 [running]")}
+		newTelegramOnError(newLogger(out, logFormatJson))(err, nil)
+
+		record := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "panic: test panic", record["error"])
+		assert.Equal(t, "goroutine 1 [running]", record["stack"])
 	})
 }
@@ -3,31 +3,151 @@ package main
 import (
 	"errors"
 	framework "github.com/kneu-messenger-pigeon/client-framework"
+	"github.com/redis/go-redis/v9"
+	tele "gopkg.in/telebot.v3"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// the same default as framework.BaseConfig uses
+const defaultRepeatScoreChangesTimeframeSeconds = 600
+
 type Config struct {
 	framework.BaseConfig
+	// framework keeps APP_SECRET private, it signs callback data as well
+	appSecret       string
 	telegramToken   string
 	telegramOffline bool
 	// for test purpose override with mock server
 	telegramURL string
+	// webhook mode is enabled when public URL is set, otherwise LongPoller is used
+	telegramWebhookURL    string
+	telegramWebhookListen string
+	telegramWebhookSecret string
+	// the webhook is shared by replicas, so it is removed on stop only when it is enabled
+	telegramWebhookRemoveOnStop bool
+	// MarkdownV2 (default) or HTML
+	parseMode tele.ParseMode
+	// disciplines per page of inline keyboard, 0 disables pagination
+	disciplinesPageSize   int
+	disciplinesTwoColumns bool
+	// how long disciplines and scores are served from cache without request to score service, 0 disables cache
+	scoreCacheTTL time.Duration
+	// deadline of update processing and count of concurrently processed updates, 0 disables the limit
+	handlerTimeout     time.Duration
+	handlerConcurrency int
+	// file to keep pending score notifications between restarts, outbox is disabled when empty
+	outboxFile string
+	// framework keeps them private, replayed notifications save message ids to the framework storage
+	redisOptions                *redis.Options
+	repeatScoreChangesTimeframe time.Duration
+	// text (default) or json
+	logFormat string
+	// address of health, readiness and metrics endpoints, admin server is disabled when empty
+	adminListen string
+	adminPprof  bool
 }
 
 func loadConfig(envFilename string) (Config, error) {
 	baseConfig, err := framework.LoadBaseConfig(envFilename, clientName)
 
 	config := Config{
-		BaseConfig:      baseConfig,
-		telegramToken:   os.Getenv("TELEGRAM_TOKEN"),
-		telegramOffline: os.Getenv("TELEGRAM_OFFLINE") == "1" || strings.ToLower(os.Getenv("TELEGRAM_OFFLINE")) == "true",
-		telegramURL:     os.Getenv("TELEGRAM_URL"),
+		BaseConfig:                  baseConfig,
+		appSecret:                   os.Getenv("APP_SECRET"),
+		telegramToken:               os.Getenv("TELEGRAM_TOKEN"),
+		telegramOffline:             os.Getenv("TELEGRAM_OFFLINE") == "1" || strings.ToLower(os.Getenv("TELEGRAM_OFFLINE")) == "true",
+		telegramURL:                 os.Getenv("TELEGRAM_URL"),
+		telegramWebhookURL:          os.Getenv("TELEGRAM_WEBHOOK_URL"),
+		telegramWebhookListen:       os.Getenv("TELEGRAM_WEBHOOK_LISTEN"),
+		telegramWebhookSecret:       os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+		telegramWebhookRemoveOnStop: os.Getenv("TELEGRAM_WEBHOOK_REMOVE_ON_STOP") == "1" || strings.ToLower(os.Getenv("TELEGRAM_WEBHOOK_REMOVE_ON_STOP")) == "true",
+		outboxFile:                  os.Getenv("OUTBOX_FILE"),
+		adminListen:                 os.Getenv("ADMIN_LISTEN"),
+		adminPprof:                  os.Getenv("ADMIN_PPROF") == "1" || strings.ToLower(os.Getenv("ADMIN_PPROF")) == "true",
+		disciplinesPageSize:         defaultDisciplinesPageSize,
+		scoreCacheTTL:               defaultScoreCacheTTL,
+		handlerTimeout:              defaultHandlerTimeout,
+		handlerConcurrency:          defaultHandlerConcurrency,
+		disciplinesTwoColumns:       os.Getenv("DISCIPLINES_TWO_COLUMNS") == "1" || strings.ToLower(os.Getenv("DISCIPLINES_TWO_COLUMNS")) == "true",
+	}
+
+	// invalid REDIS_DSN is reported by the framework
+	config.redisOptions, _ = redis.ParseURL(os.Getenv("REDIS_DSN"))
+
+	// parsed the same way as framework.LoadBaseConfig does, so message ids expire with the framework's ones
+	repeatScoreChangesTimeframeSeconds, parseErr := strconv.Atoi(os.Getenv("TIMEFRAME_TO_COMBINE_REPEAT_SCORE_CHANGES"))
+	if repeatScoreChangesTimeframeSeconds == 0 || parseErr != nil {
+		repeatScoreChangesTimeframeSeconds = defaultRepeatScoreChangesTimeframeSeconds
+	}
+	config.repeatScoreChangesTimeframe = time.Second * time.Duration(repeatScoreChangesTimeframeSeconds)
+
+	if os.Getenv("DISCIPLINES_PAGE_SIZE") != "" {
+		var parseErr error
+		config.disciplinesPageSize, parseErr = strconv.Atoi(os.Getenv("DISCIPLINES_PAGE_SIZE"))
+		if (parseErr != nil || config.disciplinesPageSize < 0) && err == nil {
+			err = errors.New("invalid DISCIPLINES_PAGE_SIZE, expected non-negative number")
+		}
+	}
+
+	if os.Getenv("SCORE_CACHE_TTL") != "" {
+		var parseErr error
+		config.scoreCacheTTL, parseErr = time.ParseDuration(os.Getenv("SCORE_CACHE_TTL"))
+		if (parseErr != nil || config.scoreCacheTTL < 0) && err == nil {
+			err = errors.New("invalid SCORE_CACHE_TTL, expected non-negative duration like 1m")
+		}
+	}
+
+	if os.Getenv("HANDLER_TIMEOUT") != "" {
+		var parseErr error
+		config.handlerTimeout, parseErr = time.ParseDuration(os.Getenv("HANDLER_TIMEOUT"))
+		if (parseErr != nil || config.handlerTimeout < 0) && err == nil {
+			err = errors.New("invalid HANDLER_TIMEOUT, expected non-negative duration like 15s")
+		}
+	}
+
+	if os.Getenv("HANDLER_CONCURRENCY") != "" {
+		var parseErr error
+		config.handlerConcurrency, parseErr = strconv.Atoi(os.Getenv("HANDLER_CONCURRENCY"))
+		if (parseErr != nil || config.handlerConcurrency < 0) && err == nil {
+			err = errors.New("invalid HANDLER_CONCURRENCY, expected non-negative number")
+		}
+	}
+
+	if config.telegramWebhookURL != "" && config.telegramWebhookListen == "" {
+		config.telegramWebhookListen = ":8080"
+	}
+
+	switch strings.ToLower(os.Getenv("LOG_FORMAT")) {
+	case "", logFormatText:
+		config.logFormat = logFormatText
+	case logFormatJson:
+		config.logFormat = logFormatJson
+	default:
+		if err == nil {
+			err = errors.New("unsupported LOG_FORMAT, expected text or json")
+		}
+	}
+
+	switch strings.ToLower(os.Getenv("TELEGRAM_PARSE_MODE")) {
+	case "", "markdownv2":
+		config.parseMode = tele.ModeMarkdownV2
+	case "html":
+		config.parseMode = tele.ModeHTML
+	default:
+		if err == nil {
+			err = errors.New("unsupported TELEGRAM_PARSE_MODE, expected MarkdownV2 or HTML")
+		}
 	}
 
 	if config.telegramToken == "" && err == nil {
 		err = errors.New("empty TELEGRAM_TOKEN")
 	}
 
+	if config.telegramWebhookURL != "" && config.telegramWebhookSecret == "" && err == nil {
+		err = errors.New("empty TELEGRAM_WEBHOOK_SECRET")
+	}
+
 	return config, err
 }
@@ -2,8 +2,10 @@ package main
 
 import (
 	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v3"
 	"os"
 	"testing"
+	"time"
 )
 
 var expectedConfig = Config{
@@ -15,6 +17,21 @@ var expectedConfig = Config{
 func loadTestBaseConfigVars() {
 	_ = os.Unsetenv("KAFKA_TIMEOUT")
 	_ = os.Unsetenv("KAFKA_ATTEMPTS")
+	_ = os.Unsetenv("TELEGRAM_WEBHOOK_URL")
+	_ = os.Unsetenv("TELEGRAM_WEBHOOK_LISTEN")
+	_ = os.Unsetenv("TELEGRAM_WEBHOOK_SECRET")
+	_ = os.Unsetenv("TELEGRAM_WEBHOOK_REMOVE_ON_STOP")
+	_ = os.Unsetenv("OUTBOX_FILE")
+	_ = os.Unsetenv("TELEGRAM_PARSE_MODE")
+	_ = os.Unsetenv("DISCIPLINES_PAGE_SIZE")
+	_ = os.Unsetenv("DISCIPLINES_TWO_COLUMNS")
+	_ = os.Unsetenv("SCORE_CACHE_TTL")
+	_ = os.Unsetenv("HANDLER_TIMEOUT")
+	_ = os.Unsetenv("HANDLER_CONCURRENCY")
+	_ = os.Unsetenv("LOG_FORMAT")
+	_ = os.Unsetenv("ADMIN_LISTEN")
+	_ = os.Unsetenv("ADMIN_PPROF")
+	_ = os.Unsetenv("TIMEFRAME_TO_COMBINE_REPEAT_SCORE_CHANGES")
 	_ = os.Setenv("APP_SECRET", "test-test")
 	_ = os.Setenv("KAFKA_HOST", "localhost:29092")
 	_ = os.Setenv("REDIS_DSN", "redis://@localhost:6400/2")
@@ -54,6 +71,329 @@ func TestLoadConfigFromEnvVars(t *testing.T) {
 	})
 }
 
+func TestLoadConfigWebhook(t *testing.T) {
+	t.Run("FromEnvVars", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TELEGRAM_WEBHOOK_URL", "https://bot.kneu.test/webhook")
+		_ = os.Setenv("TELEGRAM_WEBHOOK_LISTEN", ":8443")
+		_ = os.Setenv("TELEGRAM_WEBHOOK_SECRET", "webhook-secret")
+		_ = os.Setenv("TELEGRAM_WEBHOOK_REMOVE_ON_STOP", "true")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.True(t, actualConfig.telegramWebhookRemoveOnStop)
+		assert.True(t, makePoller(actualConfig).(*WebhookPoller).removeOnStop)
+		assert.Equal(t, "https://bot.kneu.test/webhook", actualConfig.telegramWebhookURL)
+		assert.Equal(t, ":8443", actualConfig.telegramWebhookListen)
+		assert.Equal(t, "webhook-secret", actualConfig.telegramWebhookSecret)
+		assert.IsType(t, &WebhookPoller{}, makePoller(actualConfig))
+	})
+
+	t.Run("default listen", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TELEGRAM_WEBHOOK_URL", "https://bot.kneu.test/webhook")
+		_ = os.Setenv("TELEGRAM_WEBHOOK_SECRET", "webhook-secret")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":8080", actualConfig.telegramWebhookListen)
+		assert.False(t, actualConfig.telegramWebhookRemoveOnStop)
+	})
+
+	t.Run("empty TELEGRAM_WEBHOOK_SECRET", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TELEGRAM_WEBHOOK_URL", "https://bot.kneu.test/webhook")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Equal(t, "empty TELEGRAM_WEBHOOK_SECRET", err.Error())
+	})
+
+	t.Run("long poller", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.IsType(t, &tele.LongPoller{}, makePoller(actualConfig))
+	})
+}
+
+func TestLoadConfigOutbox(t *testing.T) {
+	loadTestBaseConfigVars()
+	_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+	_ = os.Setenv("OUTBOX_FILE", "/var/lib/telegram-app/outbox.log")
+	defer loadTestBaseConfigVars()
+
+	actualConfig, err := loadConfig("")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/lib/telegram-app/outbox.log", actualConfig.outboxFile)
+}
+
+func TestLoadConfigRepeatScoreChangesTimeframe(t *testing.T) {
+	// the same values as framework.LoadBaseConfig parses
+	testCases := map[string]time.Duration{
+		"":        time.Second * defaultRepeatScoreChangesTimeframeSeconds,
+		"invalid": time.Second * defaultRepeatScoreChangesTimeframeSeconds,
+		"0":       time.Second * defaultRepeatScoreChangesTimeframeSeconds,
+		"300":     time.Second * 300,
+		"-1":      -time.Second,
+	}
+
+	for value, expected := range testCases {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TIMEFRAME_TO_COMBINE_REPEAT_SCORE_CHANGES", value)
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actualConfig.repeatScoreChangesTimeframe, "value %q", value)
+	}
+	loadTestBaseConfigVars()
+}
+
+func TestLoadConfigParseMode(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, tele.ModeMarkdownV2, actualConfig.parseMode)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TELEGRAM_PARSE_MODE", "HTML")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, tele.ModeHTML, actualConfig.parseMode)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("TELEGRAM_PARSE_MODE", "Markdown")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "TELEGRAM_PARSE_MODE")
+	})
+}
+
+func TestLoadConfigDisciplinesKeyboard(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, defaultDisciplinesPageSize, actualConfig.disciplinesPageSize)
+		assert.False(t, actualConfig.disciplinesTwoColumns)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("DISCIPLINES_PAGE_SIZE", "0")
+		_ = os.Setenv("DISCIPLINES_TWO_COLUMNS", "true")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, actualConfig.disciplinesPageSize)
+		assert.True(t, actualConfig.disciplinesTwoColumns)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("DISCIPLINES_PAGE_SIZE", "-1")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "DISCIPLINES_PAGE_SIZE")
+	})
+}
+
+func TestLoadConfigScoreCacheTTL(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, defaultScoreCacheTTL, actualConfig.scoreCacheTTL)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("SCORE_CACHE_TTL", "30s")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second*30, actualConfig.scoreCacheTTL)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("SCORE_CACHE_TTL", "30")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "SCORE_CACHE_TTL")
+	})
+}
+
+func TestLoadConfigHandlerLimiter(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, defaultHandlerTimeout, actualConfig.handlerTimeout)
+		assert.Equal(t, defaultHandlerConcurrency, actualConfig.handlerConcurrency)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("HANDLER_TIMEOUT", "5s")
+		_ = os.Setenv("HANDLER_CONCURRENCY", "0")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Second*5, actualConfig.handlerTimeout)
+		assert.Equal(t, 0, actualConfig.handlerConcurrency)
+	})
+
+	t.Run("invalid_timeout", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("HANDLER_TIMEOUT", "-1s")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "HANDLER_TIMEOUT")
+	})
+
+	t.Run("invalid_concurrency", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("HANDLER_CONCURRENCY", "many")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "HANDLER_CONCURRENCY")
+	})
+}
+
+func TestLoadConfigLogFormat(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, logFormatText, actualConfig.logFormat)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("LOG_FORMAT", "JSON")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, logFormatJson, actualConfig.logFormat)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("LOG_FORMAT", "xml")
+		defer loadTestBaseConfigVars()
+
+		_, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "LOG_FORMAT")
+	})
+}
+
+func TestLoadConfigAdminServer(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, actualConfig.adminListen)
+		assert.False(t, actualConfig.adminPprof)
+	})
+
+	t.Run("FromEnvVars", func(t *testing.T) {
+		loadTestBaseConfigVars()
+		_ = os.Setenv("TELEGRAM_TOKEN", expectedConfig.telegramToken)
+		_ = os.Setenv("ADMIN_LISTEN", ":9090")
+		_ = os.Setenv("ADMIN_PPROF", "true")
+		defer loadTestBaseConfigVars()
+
+		actualConfig, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":9090", actualConfig.adminListen)
+		assert.True(t, actualConfig.adminPprof)
+	})
+}
+
 func assertConfig(t *testing.T, expected Config, actual Config) {
 	assert.Equal(t, expected.telegramToken, actual.telegramToken)
 	assert.Equal(t, expected.telegramOffline, actual.telegramOffline)
@@ -4,12 +4,14 @@ go 1.23
 
 require (
 	github.com/VictoriaMetrics/metrics v1.35.2
+	github.com/go-redis/redismock/v9 v9.2.0
 	github.com/h2non/gock v1.2.0
 	github.com/kneu-messenger-pigeon/authorizer-client v0.1.8
 	github.com/kneu-messenger-pigeon/client-framework v0.1.53
 	github.com/kneu-messenger-pigeon/events v0.1.42
 	github.com/kneu-messenger-pigeon/score-api v0.1.12
 	github.com/kneu-messenger-pigeon/score-client v0.1.14
+	github.com/redis/go-redis/v9 v9.7.0
 	github.com/stretchr/testify v1.10.0
 	golang.org/x/time v0.10.0
 	gopkg.in/telebot.v3 v3.3.8
@@ -27,7 +29,6 @@ require (
 	github.com/kneu-messenger-pigeon/victoria-metrics-init v0.1.3 // indirect
 	github.com/pierrec/lz4/v4 v4.1.22 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/redis/go-redis/v9 v9.7.0 // indirect
 	github.com/segmentio/kafka-go v0.4.47 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/valyala/fastrand v1.1.0 // indirect
@@ -1,13 +1,98 @@
 package main
 
-import "github.com/VictoriaMetrics/metrics"
+import (
+	"fmt"
+	"github.com/VictoriaMetrics/metrics"
+	"time"
+)
 
 var (
-	OnErrorCount         = metrics.NewCounter(`error_count{type="onError"}`)
-	OnUpdateErrorCount   = metrics.NewCounter(`error_count{type="onUpdate"}`)
-	RateLimitErrorCount  = metrics.NewCounter(`error_count{type="rateLimit"}`)
-	TooManyRequestsCount = metrics.NewCounter(`error_count{type="tooManyRequests"}`)
+	OnErrorCount                  = metrics.NewCounter(`error_count{type="onError"}`)
+	OnUpdateErrorCount            = metrics.NewCounter(`error_count{type="onUpdate"}`)
+	PanicCount                    = metrics.NewCounter(`error_count{type="panic"}`)
+	HandlerTimeoutCount           = metrics.NewCounter(`error_count{type="handlerTimeout"}`)
+	HandlerQueueTimeoutCount      = metrics.NewCounter(`error_count{type="handlerQueueTimeout"}`)
+	RateLimitErrorCount           = metrics.NewCounter(`error_count{type="rateLimit"}`)
+	TooManyRequestsCount          = metrics.NewCounter(`error_count{type="tooManyRequests"}`)
+	WebhookUnauthorizedErrorCount = metrics.NewCounter(`error_count{type="webhookUnauthorized"}`)
+	WebhookBadRequestErrorCount   = metrics.NewCounter(`error_count{type="webhookBadRequest"}`)
+	WebhookErrorCount             = metrics.NewCounter(`error_count{type="webhook"}`)
+	OutboxErrorCount              = metrics.NewCounter(`error_count{type="outbox"}`)
+	ParseEntitiesErrorCount       = metrics.NewCounter(`error_count{type="parseEntities"}`)
+	CallbackPayloadErrorCount     = metrics.NewCounter(`error_count{type="callbackPayload"}`)
+	ScoreCacheRefreshErrorCount   = metrics.NewCounter(`error_count{type="scoreCacheRefresh"}`)
+	ScoreClientTimeoutCount       = metrics.NewCounter(`error_count{type="scoreClientTimeout"}`)
+	AdminServerErrorCount         = metrics.NewCounter(`error_count{type="adminServer"}`)
+	SetCommandsErrorCount         = metrics.NewCounter(`error_count{type="setCommands"}`)
 
 	DisciplinesListActionRequestTotal  = metrics.NewCounter(`request_total{type="DisciplinesListAction"}`)
 	DisciplineScoresActionRequestTotal = metrics.NewCounter(`request_total{type="DisciplineScoresAction"}`)
+	DisciplinesPageActionRequestTotal  = metrics.NewCounter(`request_total{type="DisciplinesPageAction"}`)
+
+	WebhookUpdatesTotal = metrics.NewCounter(`webhook_updates_total`)
+
+	// updates waiting for a free slot of HandlerLimiter and processed ones
+	HandlerQueueDepth = metrics.NewGauge(`handler_queue_depth`, nil)
+	HandlerInFlight   = metrics.NewGauge(`handler_in_flight`, nil)
+
+	TelegramCallSuccessTotal        = metrics.NewCounter(`telegram_call_total{outcome="success"}`)
+	TelegramCallPermanentErrorTotal = metrics.NewCounter(`telegram_call_total{outcome="permanentError"}`)
+	TelegramCallRetryExhaustedTotal = metrics.NewCounter(`telegram_call_total{outcome="retryExhausted"}`)
+	TelegramCallCancelledTotal      = metrics.NewCounter(`telegram_call_total{outcome="cancelled"}`)
+	TelegramRetryFloodTotal         = metrics.NewCounter(`telegram_retry_total{reason="flood"}`)
+	TelegramRetryTransientTotal     = metrics.NewCounter(`telegram_retry_total{reason="transient"}`)
+
+	OutboxReplayedTotal = metrics.NewCounter(`outbox_replayed_total`)
+
+	ThrottledRateTotal      = metrics.NewCounter(`throttled_total{reason="rate"}`)
+	ThrottledDuplicateTotal = metrics.NewCounter(`throttled_total{reason="duplicate"}`)
+
+	ChatRateLimitWaitDuration   = metrics.NewHistogram(`chat_rate_limit_wait_seconds`)
+	ChatRateLimiterEvictedTotal = metrics.NewCounter(`chat_rate_limiter_evicted_total`)
+
+	// 0 - closed, 1 - half-open, 2 - open
+	ScoreCircuitBreakerState         = metrics.NewGauge(`score_circuit_breaker_state`, nil)
+	ScoreCircuitBreakerRejectedTotal = metrics.NewCounter(`score_circuit_breaker_rejected_total`)
+
+	ScoreCacheHitTotal         = metrics.NewCounter(`score_cache_total{result="hit"}`)
+	ScoreCacheStaleTotal       = metrics.NewCounter(`score_cache_total{result="stale"}`)
+	ScoreCacheMissTotal        = metrics.NewCounter(`score_cache_total{result="miss"}`)
+	ScoreCacheInvalidatedTotal = metrics.NewCounter(`score_cache_invalidated_total`)
 )
+
+// ActionDurationHistogram measures controller actions, outcome is success or error.
+func ActionDurationHistogram(action string, err error) *metrics.Histogram {
+	return metrics.GetOrCreateHistogram(fmt.Sprintf(
+		`action_duration_seconds{action=%q,outcome=%q}`, action, errorOutcome(err),
+	))
+}
+
+// ScoreClientDurationHistogram measures requests to the score service, outcome is success or error.
+func ScoreClientDurationHistogram(method string, err error) *metrics.Histogram {
+	return metrics.GetOrCreateHistogram(fmt.Sprintf(
+		`score_client_duration_seconds{method=%q,outcome=%q}`, method, errorOutcome(err),
+	))
+}
+
+// TelegramApiDurationHistogram measures each attempt of Bot API call, outcome is the class of the error.
+func TelegramApiDurationHistogram(method string, errorClass sendErrorClass) *metrics.Histogram {
+	return metrics.GetOrCreateHistogram(fmt.Sprintf(
+		`telegram_api_duration_seconds{method=%q,outcome=%q}`, method, errorClass.outcome(),
+	))
+}
+
+// observeActionDuration and observeScoreClientDuration are deferred with pointer to the named error result.
+func observeActionDuration(action string, start time.Time, err *error) {
+	ActionDurationHistogram(action, *err).UpdateDuration(start)
+}
+
+func observeScoreClientDuration(method string, start time.Time, err *error) {
+	ScoreClientDurationHistogram(method, *err).UpdateDuration(start)
+}
+
+func errorOutcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}